@@ -1 +1,351 @@
 package userop
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// UserOperationBuilder implements IUserOperationBuilder. It keeps a defaults
+// layer that the current op is reset to, and a middleware stack that is run
+// in order every time an op is built.
+type UserOperationBuilder struct {
+	defaultOp         *IUserOperation
+	currOp            *IUserOperation
+	middlewareStack   []UserOperationMiddlewareFn
+	entryPointVersion EntryPointVersion
+	err               error
+}
+
+// NewUserOperationBuilder creates a builder seeded with NewDefaultUserOperation.
+func NewUserOperationBuilder() *UserOperationBuilder {
+	defaultOp := NewDefaultUserOperation()
+	return &UserOperationBuilder{
+		defaultOp: defaultOp,
+		currOp:    defaultOp.Copy(),
+	}
+}
+
+// GetSender returns the sender of the current op.
+func (b *UserOperationBuilder) GetSender() common.Address {
+	return b.currOp.Sender
+}
+
+// GetNonce returns a copy of the nonce of the current op.
+func (b *UserOperationBuilder) GetNonce() *big.Int {
+	return copyBigInt(b.currOp.Nonce)
+}
+
+// GetInitCode returns the initCode of the current op.
+func (b *UserOperationBuilder) GetInitCode() string {
+	return b.currOp.InitCode
+}
+
+// GetCallData returns the callData of the current op.
+func (b *UserOperationBuilder) GetCallData() string {
+	return b.currOp.CallData
+}
+
+// GetCallGasLimit returns a copy of the callGasLimit of the current op.
+func (b *UserOperationBuilder) GetCallGasLimit() *big.Int {
+	return copyBigInt(b.currOp.CallGasLimit)
+}
+
+// GetVerificationGasLimit returns a copy of the verificationGasLimit of the current op.
+func (b *UserOperationBuilder) GetVerificationGasLimit() *big.Int {
+	return copyBigInt(b.currOp.VerificationGasLimit)
+}
+
+// GetPreVerificationGas returns a copy of the preVerificationGas of the current op.
+func (b *UserOperationBuilder) GetPreVerificationGas() *big.Int {
+	return copyBigInt(b.currOp.PreVerificationGas)
+}
+
+// GetMaxFeePerGas returns a copy of the maxFeePerGas of the current op.
+func (b *UserOperationBuilder) GetMaxFeePerGas() *big.Int {
+	return copyBigInt(b.currOp.MaxFeePerGas)
+}
+
+// GetMaxPriorityFeePerGas returns a copy of the maxPriorityFeePerGas of the current op.
+func (b *UserOperationBuilder) GetMaxPriorityFeePerGas() *big.Int {
+	return copyBigInt(b.currOp.MaxPriorityFeePerGas)
+}
+
+// GetPaymasterAndData returns the paymasterAndData of the current op.
+func (b *UserOperationBuilder) GetPaymasterAndData() string {
+	return b.currOp.PaymasterAndData
+}
+
+// GetSignature returns the signature of the current op.
+func (b *UserOperationBuilder) GetSignature() string {
+	return b.currOp.Signature
+}
+
+// GetEip7702Auth returns a copy of the EIP-7702 authorization of the current op.
+func (b *UserOperationBuilder) GetEip7702Auth() *Eip7702Auth {
+	return b.currOp.Eip7702Auth.Copy()
+}
+
+// GetEntryPointVersion returns the EntryPoint version set with
+// SetEntryPointVersion, or "" if it is detected from the entry point address.
+func (b *UserOperationBuilder) GetEntryPointVersion() EntryPointVersion {
+	return b.entryPointVersion
+}
+
+// GetOp returns a copy of the current op.
+func (b *UserOperationBuilder) GetOp() *IUserOperation {
+	return b.currOp.Copy()
+}
+
+// SetSender sets the sender of the current op.
+func (b *UserOperationBuilder) SetSender(address common.Address) IUserOperationBuilder {
+	b.currOp.Sender = address
+	return b
+}
+
+// SetNonce sets the nonce of the current op.
+func (b *UserOperationBuilder) SetNonce(nonce *big.Int) IUserOperationBuilder {
+	b.currOp.Nonce = copyBigInt(nonce)
+	return b
+}
+
+// SetInitCode sets the initCode of the current op.
+func (b *UserOperationBuilder) SetInitCode(code string) IUserOperationBuilder {
+	b.currOp.InitCode = code
+	return b
+}
+
+// SetCallData sets the callData of the current op.
+func (b *UserOperationBuilder) SetCallData(data string) IUserOperationBuilder {
+	b.currOp.CallData = data
+	return b
+}
+
+// SetCallGasLimit sets the callGasLimit of the current op.
+func (b *UserOperationBuilder) SetCallGasLimit(gas *big.Int) IUserOperationBuilder {
+	b.currOp.CallGasLimit = copyBigInt(gas)
+	return b
+}
+
+// SetVerificationGasLimit sets the verificationGasLimit of the current op.
+func (b *UserOperationBuilder) SetVerificationGasLimit(gas *big.Int) IUserOperationBuilder {
+	b.currOp.VerificationGasLimit = copyBigInt(gas)
+	return b
+}
+
+// SetPreVerificationGas sets the preVerificationGas of the current op.
+func (b *UserOperationBuilder) SetPreVerificationGas(gas *big.Int) IUserOperationBuilder {
+	b.currOp.PreVerificationGas = copyBigInt(gas)
+	return b
+}
+
+// SetMaxFeePerGas sets the maxFeePerGas of the current op.
+func (b *UserOperationBuilder) SetMaxFeePerGas(fee *big.Int) IUserOperationBuilder {
+	b.currOp.MaxFeePerGas = copyBigInt(fee)
+	return b
+}
+
+// SetMaxPriorityFeePerGas sets the maxPriorityFeePerGas of the current op.
+func (b *UserOperationBuilder) SetMaxPriorityFeePerGas(fee *big.Int) IUserOperationBuilder {
+	b.currOp.MaxPriorityFeePerGas = copyBigInt(fee)
+	return b
+}
+
+// SetPaymasterAndData sets the paymasterAndData of the current op.
+func (b *UserOperationBuilder) SetPaymasterAndData(data string) IUserOperationBuilder {
+	b.currOp.PaymasterAndData = data
+	return b
+}
+
+// SetSignature sets the signature of the current op.
+func (b *UserOperationBuilder) SetSignature(bytes string) IUserOperationBuilder {
+	b.currOp.Signature = bytes
+	return b
+}
+
+// SetEip7702Auth sets the EIP-7702 authorization of the current op.
+func (b *UserOperationBuilder) SetEip7702Auth(auth *Eip7702Auth) IUserOperationBuilder {
+	b.currOp.Eip7702Auth = auth.Copy()
+	return b
+}
+
+// SetPartial merges the given fields into the current op. Keys use the same
+// names as IUserOperation.ToJSON. Invalid values are reported by BuildOp.
+func (b *UserOperationBuilder) SetPartial(partialOp map[string]interface{}) IUserOperationBuilder {
+	if err := applyPartial(b.currOp, partialOp); err != nil && b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// UseDefaults merges the given fields into both the defaults and the current op.
+func (b *UserOperationBuilder) UseDefaults(partialOp map[string]interface{}) IUserOperationBuilder {
+	if err := applyPartial(b.defaultOp, partialOp); err != nil && b.err == nil {
+		b.err = err
+	}
+	_ = applyPartial(b.currOp, partialOp)
+	return b
+}
+
+// ResetDefaults restores the defaults to NewDefaultUserOperation.
+func (b *UserOperationBuilder) ResetDefaults() IUserOperationBuilder {
+	b.defaultOp = NewDefaultUserOperation()
+	return b
+}
+
+// UseMiddleware appends fn to the middleware stack.
+func (b *UserOperationBuilder) UseMiddleware(fn UserOperationMiddlewareFn) IUserOperationBuilder {
+	b.middlewareStack = append(b.middlewareStack, fn)
+	return b
+}
+
+// ResetMiddleware removes every registered middleware.
+func (b *UserOperationBuilder) ResetMiddleware() IUserOperationBuilder {
+	b.middlewareStack = nil
+	return b
+}
+
+// SetEntryPointVersion overrides the EntryPoint version passed to middleware.
+// By default it is detected from the entry point address given to BuildOp.
+func (b *UserOperationBuilder) SetEntryPointVersion(version EntryPointVersion) IUserOperationBuilder {
+	b.entryPointVersion = version
+	return b
+}
+
+// BuildOp runs the middleware stack on a copy of the current op, stores the
+// result as the current op and returns an independent copy of it.
+func (b *UserOperationBuilder) BuildOp(entryPoint common.Address, chainID *big.Int) (*IUserOperation, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	ctx := &IUserOperationMiddlewareCtx{
+		Op:                b.currOp.Copy(),
+		EntryPoint:        entryPoint,
+		ChainID:           chainID,
+		EntryPointVersion: resolveEntryPointVersion(b.entryPointVersion, entryPoint),
+	}
+	for _, fn := range b.middlewareStack {
+		if err := fn(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	b.currOp = ctx.Op.Copy()
+	return b.currOp.Copy(), nil
+}
+
+// ResetOp sets the current op back to the defaults.
+func (b *UserOperationBuilder) ResetOp() IUserOperationBuilder {
+	b.currOp = b.defaultOp.Copy()
+	b.err = nil
+	return b
+}
+
+// applyPartial writes every field of partialOp into op. op is left untouched
+// if any field is invalid.
+func applyPartial(target *IUserOperation, partialOp map[string]interface{}) error {
+	op := target.Copy()
+	for key, value := range partialOp {
+		var err error
+		switch key {
+		case "sender":
+			op.Sender, err = toAddress(value)
+		case "nonce":
+			op.Nonce, err = toBigInt(value)
+		case "initCode":
+			op.InitCode, err = toHexString(value)
+		case "callData":
+			op.CallData, err = toHexString(value)
+		case "callGasLimit":
+			op.CallGasLimit, err = toBigInt(value)
+		case "verificationGasLimit":
+			op.VerificationGasLimit, err = toBigInt(value)
+		case "preVerificationGas":
+			op.PreVerificationGas, err = toBigInt(value)
+		case "maxFeePerGas":
+			op.MaxFeePerGas, err = toBigInt(value)
+		case "maxPriorityFeePerGas":
+			op.MaxPriorityFeePerGas, err = toBigInt(value)
+		case "paymasterAndData":
+			op.PaymasterAndData, err = toHexString(value)
+		case "signature":
+			op.Signature, err = toHexString(value)
+		default:
+			err = fmt.Errorf("unknown user operation field %q", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	*target = *op
+	return nil
+}
+
+func toAddress(value interface{}) (common.Address, error) {
+	switch v := value.(type) {
+	case common.Address:
+		return v, nil
+	case *common.Address:
+		if v == nil {
+			return common.Address{}, nil
+		}
+		return *v, nil
+	case string:
+		if !common.IsHexAddress(v) {
+			return common.Address{}, fmt.Errorf("%q is not a hex address", v)
+		}
+		return common.HexToAddress(v), nil
+	default:
+		return common.Address{}, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func toBigInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		if v == nil {
+			return big.NewInt(0), nil
+		}
+		return new(big.Int).Set(v), nil
+	case big.Int:
+		return new(big.Int).Set(&v), nil
+	case *hexutil.Big:
+		return new(big.Int).Set(v.ToInt()), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case string:
+		n, ok := new(big.Int).SetString(v, 0)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a number", v)
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func toHexString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return "0x", nil
+		}
+		if _, err := hexutil.Decode(v); err != nil {
+			return "", fmt.Errorf("%q is not hex data", v)
+		}
+		return v, nil
+	case []byte:
+		return hexutil.Encode(v), nil
+	case hexutil.Bytes:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
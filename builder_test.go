@@ -0,0 +1,133 @@
+package userop
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserOperationBuilder_Defaults(t *testing.T) {
+	builder := NewUserOperationBuilder()
+
+	assert.Equal(t, NewDefaultUserOperation(), builder.GetOp())
+}
+
+func TestUserOperationBuilder_GettersCopy(t *testing.T) {
+	builder := NewUserOperationBuilder()
+	builder.SetNonce(big.NewInt(1)).
+		SetCallGasLimit(big.NewInt(2)).
+		SetVerificationGasLimit(big.NewInt(3)).
+		SetPreVerificationGas(big.NewInt(4)).
+		SetMaxFeePerGas(big.NewInt(5)).
+		SetMaxPriorityFeePerGas(big.NewInt(6))
+
+	for _, get := range []func() *big.Int{
+		builder.GetNonce,
+		builder.GetCallGasLimit,
+		builder.GetVerificationGasLimit,
+		builder.GetPreVerificationGas,
+		builder.GetMaxFeePerGas,
+		builder.GetMaxPriorityFeePerGas,
+	} {
+		get().SetInt64(100)
+	}
+
+	op := builder.GetOp()
+	assert.Equal(t, int64(1), op.Nonce.Int64())
+	assert.Equal(t, int64(2), op.CallGasLimit.Int64())
+	assert.Equal(t, int64(3), op.VerificationGasLimit.Int64())
+	assert.Equal(t, int64(4), op.PreVerificationGas.Int64())
+	assert.Equal(t, int64(5), op.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(6), op.MaxPriorityFeePerGas.Int64())
+}
+
+func TestUserOperationBuilder_SetPartial(t *testing.T) {
+	sender := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	builder := NewUserOperationBuilder()
+
+	builder.SetPartial(map[string]interface{}{
+		"sender":       sender,
+		"nonce":        "0x2a",
+		"callData":     []byte{0xde, 0xad},
+		"callGasLimit": 100000,
+	})
+
+	assert.Equal(t, sender, builder.GetSender())
+	assert.Equal(t, int64(42), builder.GetNonce().Int64())
+	assert.Equal(t, "0xdead", builder.GetCallData())
+	assert.Equal(t, int64(100000), builder.GetCallGasLimit().Int64())
+}
+
+func TestUserOperationBuilder_SetPartialInvalid(t *testing.T) {
+	builder := NewUserOperationBuilder()
+
+	builder.SetPartial(map[string]interface{}{"nonce": "not a number"})
+
+	_, err := builder.BuildOp(common.Address{}, big.NewInt(1))
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), builder.GetNonce().Int64())
+}
+
+func TestUserOperationBuilder_UseDefaultsAndResetOp(t *testing.T) {
+	builder := NewUserOperationBuilder()
+
+	builder.UseDefaults(map[string]interface{}{"signature": "0xbeef"})
+	builder.SetCallData("0x1234")
+	assert.Equal(t, "0xbeef", builder.GetSignature())
+
+	builder.ResetOp()
+	assert.Equal(t, "0x", builder.GetCallData())
+	assert.Equal(t, "0xbeef", builder.GetSignature())
+
+	builder.ResetDefaults().ResetOp()
+	assert.Equal(t, "0x", builder.GetSignature())
+}
+
+func TestUserOperationBuilder_BuildOp(t *testing.T) {
+	entryPoint := common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+	chainID := big.NewInt(1)
+
+	var order []string
+	builder := NewUserOperationBuilder()
+	builder.UseMiddleware(func(ctx *IUserOperationMiddlewareCtx) error {
+		order = append(order, "first")
+		assert.Equal(t, entryPoint, ctx.EntryPoint)
+		assert.Equal(t, chainID, ctx.ChainID)
+		ctx.Op.CallGasLimit = big.NewInt(1)
+		return nil
+	}).UseMiddleware(func(ctx *IUserOperationMiddlewareCtx) error {
+		order = append(order, "second")
+		ctx.Op.CallGasLimit.Add(ctx.Op.CallGasLimit, big.NewInt(1))
+		return nil
+	})
+
+	op, err := builder.BuildOp(entryPoint, chainID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, order)
+	assert.Equal(t, int64(2), op.CallGasLimit.Int64())
+	assert.Equal(t, int64(2), builder.GetCallGasLimit().Int64())
+
+	// The returned op must not alias the builder's state.
+	op.CallGasLimit.SetInt64(100)
+	assert.Equal(t, int64(2), builder.GetCallGasLimit().Int64())
+}
+
+func TestUserOperationBuilder_BuildOpMiddlewareError(t *testing.T) {
+	failure := errors.New("middleware failed")
+	builder := NewUserOperationBuilder()
+	builder.UseMiddleware(func(ctx *IUserOperationMiddlewareCtx) error {
+		ctx.Op.Signature = "0x01"
+		return failure
+	})
+
+	_, err := builder.BuildOp(common.Address{}, big.NewInt(1))
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, "0x", builder.GetSignature())
+
+	builder.ResetMiddleware()
+	_, err = builder.BuildOp(common.Address{}, big.NewInt(1))
+	assert.NoError(t, err)
+}
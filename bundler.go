@@ -0,0 +1,279 @@
+package userop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/withsilasogar/userop/models"
+)
+
+// BundlerClient is a typed client for the eth_* user operation methods of
+// an ERC-4337 bundler. Ops are sent in the JSON shape of the EntryPoint
+// version detected from the entry point address, unless one is set with
+// SetEntryPointVersion.
+type BundlerClient struct {
+	provider          *BundlerJsonRpcProvider
+	entryPointVersion EntryPointVersion
+}
+
+// UserOperationGasEstimate is the result of eth_estimateUserOperationGas.
+// PaymasterVerificationGasLimit is only returned for EntryPoint v0.7 and later.
+type UserOperationGasEstimate struct {
+	PreVerificationGas            *big.Int
+	VerificationGasLimit          *big.Int
+	CallGasLimit                  *big.Int
+	PaymasterVerificationGasLimit *big.Int
+}
+
+// UserOperationByHash is the result of eth_getUserOperationByHash. The
+// transaction and block fields are zero while the op is still pending.
+type UserOperationByHash struct {
+	UserOperation   *IUserOperation
+	EntryPoint      common.Address
+	TransactionHash common.Hash
+	BlockHash       common.Hash
+	BlockNumber     *big.Int
+}
+
+// UserOperationReceipt is the result of eth_getUserOperationReceipt.
+// Logs are the logs emitted by the op, Receipt the receipt of the bundle transaction.
+type UserOperationReceipt struct {
+	UserOpHash    common.Hash
+	EntryPoint    common.Address
+	Sender        common.Address
+	Nonce         *big.Int
+	Paymaster     common.Address
+	ActualGasCost *big.Int
+	ActualGasUsed *big.Int
+	Success       bool
+	Reason        string
+	Logs          []*types.Log
+	Receipt       *types.Receipt
+}
+
+// NewBundlerClient creates a BundlerClient on top of provider.
+func NewBundlerClient(provider *BundlerJsonRpcProvider) *BundlerClient {
+	return &BundlerClient{provider: provider}
+}
+
+// SetEntryPointVersion sets the EntryPoint version ops are sent as, for an
+// entry point deployed at a non-canonical address.
+func (b *BundlerClient) SetEntryPointVersion(version EntryPointVersion) *BundlerClient {
+	b.entryPointVersion = version
+	return b
+}
+
+// SendUserOperation submits op to the bundler and returns its userOpHash.
+func (b *BundlerClient) SendUserOperation(ctx context.Context, op *IUserOperation, entryPoint common.Address) (common.Hash, error) {
+	opJSON, err := OpToJSON(op, resolveEntryPointVersion(b.entryPointVersion, entryPoint))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	var userOpHash common.Hash
+	if err := b.provider.CallContext(ctx, &userOpHash, "eth_sendUserOperation", opJSON, entryPoint); err != nil {
+		return common.Hash{}, err
+	}
+	return userOpHash, nil
+}
+
+// EstimateUserOperationGas estimates the gas limits of op.
+func (b *BundlerClient) EstimateUserOperationGas(ctx context.Context, op *IUserOperation, entryPoint common.Address) (*UserOperationGasEstimate, error) {
+	opJSON, err := OpToJSON(op, resolveEntryPointVersion(b.entryPointVersion, entryPoint))
+	if err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	if err := b.provider.CallContext(ctx, &raw, "eth_estimateUserOperationGas", opJSON, entryPoint); err != nil {
+		return nil, err
+	}
+	result, err := (&models.GasEstimate{}).FromJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gas estimate: %w", err)
+	}
+
+	estimate := &UserOperationGasEstimate{}
+	if estimate.PreVerificationGas, err = toBigInt(result.PreVerificationGas); err != nil {
+		return nil, fmt.Errorf("invalid preVerificationGas: %w", err)
+	}
+	if estimate.VerificationGasLimit, err = toBigInt(result.GetVerificationGasLimit()); err != nil {
+		return nil, fmt.Errorf("invalid verificationGasLimit: %w", err)
+	}
+	if estimate.CallGasLimit, err = toBigInt(result.CallGasLimit); err != nil {
+		return nil, fmt.Errorf("invalid callGasLimit: %w", err)
+	}
+	if result.PaymasterVerificationGasLimit != nil {
+		if estimate.PaymasterVerificationGasLimit, err = toBigInt(*result.PaymasterVerificationGasLimit); err != nil {
+			return nil, fmt.Errorf("invalid paymasterVerificationGasLimit: %w", err)
+		}
+	}
+	return estimate, nil
+}
+
+// GetUserOperationByHash returns the op with userOpHash, or nil if the
+// bundler does not know it.
+func (b *BundlerClient) GetUserOperationByHash(ctx context.Context, userOpHash common.Hash) (*UserOperationByHash, error) {
+	var result *struct {
+		UserOperation   json.RawMessage `json:"userOperation"`
+		EntryPoint      common.Address  `json:"entryPoint"`
+		TransactionHash *common.Hash    `json:"transactionHash"`
+		BlockHash       *common.Hash    `json:"blockHash"`
+		BlockNumber     *hexutil.Big    `json:"blockNumber"`
+	}
+	if err := b.provider.CallContext(ctx, &result, "eth_getUserOperationByHash", userOpHash); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	op, err := decodeUserOperation(result.UserOperation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode user operation: %w", err)
+	}
+	byHash := &UserOperationByHash{
+		UserOperation: op,
+		EntryPoint:    result.EntryPoint,
+		BlockNumber:   (*big.Int)(result.BlockNumber),
+	}
+	if result.TransactionHash != nil {
+		byHash.TransactionHash = *result.TransactionHash
+	}
+	if result.BlockHash != nil {
+		byHash.BlockHash = *result.BlockHash
+	}
+	return byHash, nil
+}
+
+// GetUserOperationReceipt returns the receipt of the op with userOpHash, or
+// nil if it has not been included yet.
+func (b *BundlerClient) GetUserOperationReceipt(ctx context.Context, userOpHash common.Hash) (*UserOperationReceipt, error) {
+	var result *struct {
+		UserOpHash    common.Hash    `json:"userOpHash"`
+		EntryPoint    common.Address `json:"entryPoint"`
+		Sender        common.Address `json:"sender"`
+		Nonce         *hexutil.Big   `json:"nonce"`
+		Paymaster     common.Address `json:"paymaster"`
+		ActualGasCost *hexutil.Big   `json:"actualGasCost"`
+		ActualGasUsed *hexutil.Big   `json:"actualGasUsed"`
+		Success       bool           `json:"success"`
+		Reason        string         `json:"reason"`
+		Logs          []*types.Log   `json:"logs"`
+		Receipt       *types.Receipt `json:"receipt"`
+	}
+	if err := b.provider.CallContext(ctx, &result, "eth_getUserOperationReceipt", userOpHash); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	return &UserOperationReceipt{
+		UserOpHash:    result.UserOpHash,
+		EntryPoint:    result.EntryPoint,
+		Sender:        result.Sender,
+		Nonce:         (*big.Int)(result.Nonce),
+		Paymaster:     result.Paymaster,
+		ActualGasCost: (*big.Int)(result.ActualGasCost),
+		ActualGasUsed: (*big.Int)(result.ActualGasUsed),
+		Success:       result.Success,
+		Reason:        result.Reason,
+		Logs:          result.Logs,
+		Receipt:       result.Receipt,
+	}, nil
+}
+
+// SupportedEntryPoints returns the entry points the bundler accepts ops for.
+func (b *BundlerClient) SupportedEntryPoints(ctx context.Context) ([]common.Address, error) {
+	var entryPoints []common.Address
+	if err := b.provider.CallContext(ctx, &entryPoints, "eth_supportedEntryPoints"); err != nil {
+		return nil, err
+	}
+	return entryPoints, nil
+}
+
+// decodeUserOperation decodes an op returned by a bundler in either the v0.6
+// shape or the unpacked v0.7 shape.
+func decodeUserOperation(data json.RawMessage) (*IUserOperation, error) {
+	var op struct {
+		Sender                        common.Address  `json:"sender"`
+		Nonce                         *hexutil.Big    `json:"nonce"`
+		InitCode                      hexutil.Bytes   `json:"initCode"`
+		Factory                       hexutil.Bytes   `json:"factory"`
+		FactoryData                   hexutil.Bytes   `json:"factoryData"`
+		CallData                      hexutil.Bytes   `json:"callData"`
+		CallGasLimit                  *hexutil.Big    `json:"callGasLimit"`
+		VerificationGasLimit          *hexutil.Big    `json:"verificationGasLimit"`
+		PreVerificationGas            *hexutil.Big    `json:"preVerificationGas"`
+		MaxFeePerGas                  *hexutil.Big    `json:"maxFeePerGas"`
+		MaxPriorityFeePerGas          *hexutil.Big    `json:"maxPriorityFeePerGas"`
+		PaymasterAndData              hexutil.Bytes   `json:"paymasterAndData"`
+		Paymaster                     *common.Address `json:"paymaster"`
+		PaymasterVerificationGasLimit *hexutil.Big    `json:"paymasterVerificationGasLimit"`
+		PaymasterPostOpGasLimit       *hexutil.Big    `json:"paymasterPostOpGasLimit"`
+		PaymasterData                 hexutil.Bytes   `json:"paymasterData"`
+		Signature                     hexutil.Bytes   `json:"signature"`
+		Eip7702Auth                   *struct {
+			ChainID *hexutil.Big   `json:"chainId"`
+			Address common.Address `json:"address"`
+			Nonce   hexutil.Uint64 `json:"nonce"`
+			YParity hexutil.Uint64 `json:"yParity"`
+			R       *hexutil.Big   `json:"r"`
+			S       *hexutil.Big   `json:"s"`
+		} `json:"eip7702Auth"`
+	}
+	if err := json.Unmarshal(data, &op); err != nil {
+		return nil, err
+	}
+
+	decoded := &IUserOperation{
+		Sender:               op.Sender,
+		Nonce:                copyBigInt((*big.Int)(op.Nonce)),
+		InitCode:             hexutil.Encode(op.InitCode),
+		CallData:             hexutil.Encode(op.CallData),
+		CallGasLimit:         copyBigInt((*big.Int)(op.CallGasLimit)),
+		VerificationGasLimit: copyBigInt((*big.Int)(op.VerificationGasLimit)),
+		PreVerificationGas:   copyBigInt((*big.Int)(op.PreVerificationGas)),
+		MaxFeePerGas:         copyBigInt((*big.Int)(op.MaxFeePerGas)),
+		MaxPriorityFeePerGas: copyBigInt((*big.Int)(op.MaxPriorityFeePerGas)),
+		PaymasterAndData:     hexutil.Encode(op.PaymasterAndData),
+		Signature:            hexutil.Encode(op.Signature),
+	}
+	if len(op.Factory) > 0 {
+		factory := op.Factory
+		if isEip7702InitCode(factory) {
+			// Bundlers may return the EIP-7702 marker unpadded as "0x7702".
+			factory = common.RightPadBytes(factory, common.AddressLength)
+		}
+		decoded.InitCode = hexutil.Encode(append(factory, op.FactoryData...))
+	}
+	if op.Paymaster != nil {
+		paymasterAndData, err := PackPaymasterAndData(
+			*op.Paymaster,
+			(*big.Int)(op.PaymasterVerificationGasLimit),
+			(*big.Int)(op.PaymasterPostOpGasLimit),
+			hexutil.Encode(op.PaymasterData),
+		)
+		if err != nil {
+			return nil, err
+		}
+		decoded.PaymasterAndData = paymasterAndData
+	}
+	if op.Eip7702Auth != nil {
+		decoded.Eip7702Auth = &Eip7702Auth{
+			ChainID: copyBigInt((*big.Int)(op.Eip7702Auth.ChainID)),
+			Address: op.Eip7702Auth.Address,
+			Nonce:   uint64(op.Eip7702Auth.Nonce),
+			YParity: uint8(op.Eip7702Auth.YParity),
+			R:       copyBigInt((*big.Int)(op.Eip7702Auth.R)),
+			S:       copyBigInt((*big.Int)(op.Eip7702Auth.S)),
+		}
+	}
+	return decoded, nil
+}
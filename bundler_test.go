@@ -0,0 +1,214 @@
+package userop
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop/constants"
+)
+
+func newTestBundlerClient(t *testing.T, handlers map[string]rpcHandler) *BundlerClient {
+	t.Helper()
+	server, _ := newTestRPCServer(t, handlers)
+	provider, err := NewBundlerJsonRpcProvider(server.URL)
+	require.NoError(t, err)
+	return NewBundlerClient(provider)
+}
+
+func TestBundlerClient_SendUserOperation(t *testing.T) {
+	userOpHash := common.HexToHash("0x46defb203cb9d4d91e5d5c1648d75f7831dc4f1a944d51a0672d0bba30cc4811")
+	bundler := newTestBundlerClient(t, map[string]rpcHandler{
+		"eth_sendUserOperation": func(params []json.RawMessage) (interface{}, error) {
+			var op map[string]string
+			var entryPoint common.Address
+			if err := decodeParams(params, &op, &entryPoint); err != nil {
+				return nil, err
+			}
+			assert.Equal(t, common.HexToAddress(constants.ENTRY_POINT_V07), entryPoint)
+			assert.Equal(t, "0x1234", op["callData"])
+			assert.NotContains(t, op, "initCode", "v0.7 ops use the unpacked shape")
+			return userOpHash, nil
+		},
+	})
+
+	op := NewDefaultUserOperation()
+	op.CallData = "0x1234"
+	hash, err := bundler.SendUserOperation(context.Background(), op, common.HexToAddress(constants.ENTRY_POINT_V07))
+	require.NoError(t, err)
+	assert.Equal(t, userOpHash, hash)
+}
+
+func TestBundlerClient_EstimateUserOperationGas(t *testing.T) {
+	bundler := newTestBundlerClient(t, map[string]rpcHandler{
+		"eth_estimateUserOperationGas": func(params []json.RawMessage) (interface{}, error) {
+			return map[string]string{
+				"preVerificationGas": "0xb3b0",
+				"verificationGas":    "0x186a0",
+				"callGasLimit":       "0x8214",
+			}, nil
+		},
+	})
+
+	estimate, err := bundler.EstimateUserOperationGas(context.Background(), NewDefaultUserOperation(), common.HexToAddress(constants.ENTRY_POINT))
+	require.NoError(t, err)
+	assert.Equal(t, int64(46000), estimate.PreVerificationGas.Int64())
+	assert.Equal(t, int64(100000), estimate.VerificationGasLimit.Int64())
+	assert.Equal(t, int64(33300), estimate.CallGasLimit.Int64())
+	assert.Nil(t, estimate.PaymasterVerificationGasLimit)
+}
+
+func TestBundlerClient_EstimateUserOperationGasVersion(t *testing.T) {
+	entryPoint := common.HexToAddress("0x0000000000000000000000000000000000e4337")
+	bundler := newTestBundlerClient(t, map[string]rpcHandler{
+		"eth_estimateUserOperationGas": func(params []json.RawMessage) (interface{}, error) {
+			var op map[string]string
+			if err := decodeParams(params, &op, nil); err != nil {
+				return nil, err
+			}
+			assert.NotContains(t, op, "initCode", "the version set on the client decides the shape")
+			return map[string]string{
+				"preVerificationGas":            "46000",
+				"verificationGasLimit":          "100000",
+				"callGasLimit":                  "33300",
+				"paymasterVerificationGasLimit": "0x7530",
+			}, nil
+		},
+	})
+	bundler.SetEntryPointVersion(EntryPointV07)
+
+	estimate, err := bundler.EstimateUserOperationGas(context.Background(), NewDefaultUserOperation(), entryPoint)
+	require.NoError(t, err)
+	assert.Equal(t, int64(46000), estimate.PreVerificationGas.Int64(), "decimal quantities are accepted")
+	assert.Equal(t, int64(100000), estimate.VerificationGasLimit.Int64())
+	assert.Equal(t, int64(33300), estimate.CallGasLimit.Int64())
+	assert.Equal(t, int64(30000), estimate.PaymasterVerificationGasLimit.Int64())
+}
+
+func TestBundlerClient_GetUserOperationByHash(t *testing.T) {
+	txHash := common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+	found := true
+	bundler := newTestBundlerClient(t, map[string]rpcHandler{
+		"eth_getUserOperationByHash": func(params []json.RawMessage) (interface{}, error) {
+			if !found {
+				return nil, nil
+			}
+			return map[string]interface{}{
+				"userOperation": map[string]string{
+					"sender":                        "0x8ba1f109551bD432803012645Ac136ddd64DBA72",
+					"nonce":                         "0x7",
+					"factory":                       "0x91e60e0613810449d098b0b5ec8b51a0fe8c8985",
+					"factoryData":                   "0x5fbfb9cf",
+					"callData":                      "0x1234",
+					"callGasLimit":                  "0x1d4c0",
+					"verificationGasLimit":          "0x6ddd0",
+					"preVerificationGas":            "0xbb80",
+					"maxFeePerGas":                  "0x6fc23ac00",
+					"maxPriorityFeePerGas":          "0x59682f00",
+					"paymaster":                     "0xe93eca6595fe94091dc1af46aac2a8b5d7990770",
+					"paymasterVerificationGasLimit": "0xea60",
+					"paymasterPostOpGasLimit":       "0x2710",
+					"paymasterData":                 "0xabcd",
+					"signature":                     "0x",
+				},
+				"entryPoint":      constants.ENTRY_POINT_V07,
+				"transactionHash": txHash,
+				"blockHash":       txHash,
+				"blockNumber":     "0x1ff",
+			}, nil
+		},
+	})
+
+	result, err := bundler.GetUserOperationByHash(context.Background(), common.Hash{0x01})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, newTestOpV07(t), result.UserOperation)
+	assert.Equal(t, common.HexToAddress(constants.ENTRY_POINT_V07), result.EntryPoint)
+	assert.Equal(t, txHash, result.TransactionHash)
+	assert.Equal(t, int64(0x1ff), result.BlockNumber.Int64())
+
+	found = false
+	result, err = bundler.GetUserOperationByHash(context.Background(), common.Hash{0x01})
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestBundlerClient_GetUserOperationReceipt(t *testing.T) {
+	userOpHash := common.HexToHash("0x46defb203cb9d4d91e5d5c1648d75f7831dc4f1a944d51a0672d0bba30cc4811")
+	txHash := common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+	log := map[string]interface{}{
+		"address":          constants.ENTRY_POINT,
+		"topics":           []common.Hash{userOpHash},
+		"data":             "0x",
+		"blockNumber":      "0x1ff",
+		"transactionHash":  txHash,
+		"transactionIndex": "0x0",
+		"blockHash":        txHash,
+		"logIndex":         "0x3",
+		"removed":          false,
+	}
+	found := true
+	bundler := newTestBundlerClient(t, map[string]rpcHandler{
+		"eth_getUserOperationReceipt": func(params []json.RawMessage) (interface{}, error) {
+			if !found {
+				return nil, nil
+			}
+			return map[string]interface{}{
+				"userOpHash":    userOpHash,
+				"entryPoint":    constants.ENTRY_POINT,
+				"sender":        "0x8ba1f109551bD432803012645Ac136ddd64DBA72",
+				"nonce":         "0x7",
+				"paymaster":     common.Address{},
+				"actualGasCost": "0x1319718a5000",
+				"actualGasUsed": "0x222e0",
+				"success":       true,
+				"reason":        "",
+				"logs":          []interface{}{log},
+				"receipt": map[string]interface{}{
+					"type":              "0x2",
+					"status":            "0x1",
+					"cumulativeGasUsed": "0x30d40",
+					"logsBloom":         types.Bloom{},
+					"logs":              []interface{}{log},
+					"transactionHash":   txHash,
+					"gasUsed":           "0x30d40",
+					"blockNumber":       "0x1ff",
+					"transactionIndex":  "0x0",
+				},
+			}, nil
+		},
+	})
+
+	receipt, err := bundler.GetUserOperationReceipt(context.Background(), userOpHash)
+	require.NoError(t, err)
+	require.NotNil(t, receipt)
+	assert.Equal(t, userOpHash, receipt.UserOpHash)
+	assert.True(t, receipt.Success)
+	assert.Equal(t, int64(21000000000000), receipt.ActualGasCost.Int64())
+	assert.Equal(t, int64(140000), receipt.ActualGasUsed.Int64())
+	require.Len(t, receipt.Logs, 1)
+	assert.Equal(t, uint(3), receipt.Logs[0].Index)
+	assert.Equal(t, txHash, receipt.Receipt.TxHash)
+	assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Receipt.Status)
+
+	found = false
+	receipt, err = bundler.GetUserOperationReceipt(context.Background(), userOpHash)
+	require.NoError(t, err)
+	assert.Nil(t, receipt)
+}
+
+func TestBundlerClient_SupportedEntryPoints(t *testing.T) {
+	bundler := newTestBundlerClient(t, map[string]rpcHandler{
+		"eth_supportedEntryPoints": func([]json.RawMessage) (interface{}, error) {
+			return []string{constants.ENTRY_POINT, constants.ENTRY_POINT_V07}, nil
+		},
+	})
+
+	entryPoints, err := bundler.SupportedEntryPoints(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{common.HexToAddress(constants.ENTRY_POINT), common.HexToAddress(constants.ENTRY_POINT_V07)}, entryPoints)
+}
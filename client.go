@@ -2,37 +2,92 @@ package userop
 
 import (
 	"context"
+	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/extensions"
+	"github.com/withsilasogar/userop/typechain"
 )
 
 // Client for interacting with an ERC-4337 bundler.
 type Client struct {
-	web3Client   *rpc.Client
-	chainId      *big.Int
-	entryPoint   common.Address
-	waitTimeout  time.Duration
-	waitInterval time.Duration
+	provider          *BundlerJsonRpcProvider
+	chainId           *big.Int
+	entryPoint        common.Address
+	entryPointVersion EntryPointVersion
+	entryPointAbi     abi.ABI
+	waitTimeout       time.Duration
+	waitInterval      time.Duration
 }
 
-// NewClient initializes a new Client.
+// NewClient initializes a new Client. A client on a SocketConnector cannot
+// reconnect: once the channel closes, create a new client.
 func NewClient(rpcUrl string, opts *IClientOpts) (*Client, error) {
-	client, err := rpc.DialContext(context.Background(), rpcUrl)
+	if opts == nil {
+		opts = &IClientOpts{}
+	}
+
+	providerOpts := &IProviderOpts{
+		EndpointOpts: map[string]*IEndpointOpts{
+			EndpointNode:    opts.RpcOpts,
+			EndpointBundler: opts.BundlerRpcOpts,
+		},
+		BroadcastUserOperations: opts.BroadcastUserOperations,
+	}
+	bundlerRpcs := opts.FallbackBundlerRpcs
+	if opts.OverrideBundlerRpc != "" {
+		bundlerRpcs = append([]string{opts.OverrideBundlerRpc}, bundlerRpcs...)
+	}
+	if len(bundlerRpcs) > 0 {
+		providerOpts.FailoverEndpoints = map[string][]string{EndpointBundler: bundlerRpcs}
+	}
+
+	var provider *BundlerJsonRpcProvider
+	if opts.SocketConnector != nil {
+		rpcClient, err := dialStreamChannel(context.Background(), opts.SocketConnector())
+		if err != nil {
+			return nil, err
+		}
+		provider = NewBundlerJsonRpcProviderWithClient(rpcClient)
+		if opts.RpcOpts != nil {
+			provider.endpoints[EndpointNode].timeout = opts.RpcOpts.Timeout
+		}
+		if err := provider.applyOpts(providerOpts); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		provider, err = NewBundlerJsonRpcProviderWithOpts(rpcUrl, providerOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entryPoint := opts.EntryPoint
+	if entryPoint == (common.Address{}) {
+		entryPoint = common.HexToAddress(constants.ENTRY_POINT)
+	}
+
+	entryPointAbi, err := abi.JSON(strings.NewReader(typechain.EntryPointContract))
 	if err != nil {
 		return nil, err
 	}
 
-	entryPoint := common.HexToAddress(constants.ENTRY_POINT)
-
 	return &Client{
-		web3Client:   client,
-		entryPoint:   entryPoint,
-		waitTimeout:  30 * time.Second,
-		waitInterval: 5 * time.Second,
+		provider:          provider,
+		entryPoint:        entryPoint,
+		entryPointVersion: resolveEntryPointVersion(opts.EntryPointVersion, entryPoint),
+		entryPointAbi:     entryPointAbi,
+		waitTimeout:       30 * time.Second,
+		waitInterval:      5 * time.Second,
 	}, nil
 }
 
@@ -43,17 +98,134 @@ func Init(rpcUrl string, opts *IClientOpts) (*Client, error) {
 		return nil, err
 	}
 
-	var chainId *big.Int
-	err = client.web3Client.CallContext(context.Background(), &chainId, "eth_chainId")
+	var chainId hexutil.Big
+	err = client.provider.CallContext(context.Background(), &chainId, "eth_chainId")
 	if err != nil {
 		return nil, err
 	}
-	client.chainId = chainId
+	client.chainId = chainId.ToInt()
 
 	return client, nil
 }
 
 // BuildUserOperation builds a user operation using the provided builder.
+// The build uses the EntryPoint version of the client, so middleware signs
+// the same hash the client sends the op with. The version of the builder is
+// restored afterwards.
 func (c *Client) BuildUserOperation(builder IUserOperationBuilder) (*IUserOperation, error) {
+	previous := builder.GetEntryPointVersion()
+	builder.SetEntryPointVersion(c.entryPointVersion)
+	defer builder.SetEntryPointVersion(previous)
 	return builder.BuildOp(c.entryPoint, c.chainId)
 }
+
+// SendUserOperation builds a user operation and submits it to the bundler.
+// With DryRun set the op is only built and its hash computed locally.
+func (c *Client) SendUserOperation(builder IUserOperationBuilder, opts *ISendUserOperationOpts) (*ISendUserOperationResponse, error) {
+	if opts == nil {
+		opts = &ISendUserOperationOpts{}
+	}
+
+	op, err := c.BuildUserOperation(builder)
+	if err != nil {
+		return nil, err
+	}
+	if opts.OnBuild != nil {
+		opts.OnBuild(op)
+	}
+
+	var (
+		userOpHash  string
+		blockNumber uint64
+	)
+	if opts.DryRun {
+		hash, err := getUserOpHashForVersion(op, c.entryPoint, c.chainId, c.entryPointVersion)
+		if err != nil {
+			return nil, err
+		}
+		userOpHash = hexutil.Encode(hash)
+	} else {
+		opJSON, err := OpToJSON(op, c.entryPointVersion)
+		if err != nil {
+			return nil, err
+		}
+		// Read before sending, so the op cannot be included in an earlier block.
+		blockNumber, err = ethclient.NewClient(c.provider.Client).BlockNumber(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block number: %w", err)
+		}
+		err = c.provider.CallContext(context.Background(), &userOpHash, "eth_sendUserOperation", opJSON, c.entryPoint.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("failed to send user operation: %w", err)
+		}
+	}
+	builder.ResetOp()
+
+	return &ISendUserOperationResponse{
+		UserOpHash:  userOpHash,
+		BlockNumber: blockNumber,
+		Wait: func() (*FilterEvent, error) {
+			if opts.DryRun {
+				return nil, nil
+			}
+			return c.wait(context.Background(), userOpHash, blockNumber)
+		},
+	}, nil
+}
+
+// wait polls the EntryPoint from fromBlock until the UserOperationEvent for
+// userOpHash is emitted or the wait timeout is reached.
+func (c *Client) wait(ctx context.Context, userOpHash string, fromBlock uint64) (*FilterEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.waitTimeout)
+	defer cancel()
+
+	ethClient := ethclient.NewClient(c.provider.Client)
+
+	event := c.entryPointAbi.Events["UserOperationEvent"]
+	filter := extensions.NewUserOperationEventFilter(c.entryPoint, event.ID.Hex(), new(big.Int).SetUint64(fromBlock), nil, userOpHash)
+	for {
+		logs, err := ethClient.FilterLogs(ctx, filter.ToFilterQuery())
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+		if len(logs) > 0 {
+			return c.decodeUserOperationEvent(logs[0])
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for user operation %s", userOpHash)
+		case <-time.After(c.waitInterval):
+		}
+	}
+}
+
+// decodeUserOperationEvent decodes a UserOperationEvent log into a FilterEvent.
+func (c *Client) decodeUserOperationEvent(log types.Log) (*FilterEvent, error) {
+	if len(log.Topics) != 4 {
+		return nil, fmt.Errorf("unexpected UserOperationEvent topics: %d", len(log.Topics))
+	}
+
+	var data struct {
+		Nonce         *big.Int
+		Success       bool
+		ActualGasCost *big.Int
+		ActualGasUsed *big.Int
+	}
+	if err := c.entryPointAbi.UnpackIntoInterface(&data, "UserOperationEvent", log.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode UserOperationEvent: %w", err)
+	}
+
+	return &FilterEvent{
+		UserOpHash:      log.Topics[1],
+		Sender:          common.BytesToAddress(log.Topics[2].Bytes()),
+		Paymaster:       common.BytesToAddress(log.Topics[3].Bytes()),
+		Nonce:           data.Nonce,
+		Success:         data.Success,
+		ActualGasCost:   data.ActualGasCost,
+		ActualGasUsed:   data.ActualGasUsed,
+		TransactionHash: log.TxHash,
+		BlockHash:       log.BlockHash,
+		BlockNumber:     log.BlockNumber,
+	}, nil
+}
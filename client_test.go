@@ -0,0 +1,369 @@
+package userop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/utils"
+)
+
+// rpcHandler answers a single JSON-RPC method on a test server.
+type rpcHandler func(params []json.RawMessage) (interface{}, error)
+
+// decodeParams unmarshals params into targets, skipping nil targets. Handlers
+// run on the server goroutine, so they report bad params as an error that
+// fails the call on the test goroutine.
+func decodeParams(params []json.RawMessage, targets ...interface{}) error {
+	if len(params) != len(targets) {
+		return fmt.Errorf("expected %d params, got %d", len(targets), len(params))
+	}
+	for i, target := range targets {
+		if target == nil {
+			continue
+		}
+		if err := json.Unmarshal(params[i], target); err != nil {
+			return fmt.Errorf("param %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// newTestRPCServer starts an HTTP JSON-RPC server that dispatches to handlers
+// and records the methods it has been called with.
+func newTestRPCServer(t *testing.T, handlers map[string]rpcHandler) (*httptest.Server, *[]string) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	type request struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	handle := func(req request) map[string]interface{} {
+		mu.Lock()
+		calls = append(calls, req.Method)
+		mu.Unlock()
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		handler, ok := handlers[req.Method]
+		if !ok {
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		} else if result, err := handler(req.Params); err != nil {
+			resp["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		return resp
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		// Batches are answered element by element, in order.
+		if len(body) > 0 && body[0] == '[' {
+			var reqs []request
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			resps := make([]map[string]interface{}, len(reqs))
+			for i, req := range reqs {
+				resps[i] = handle(req)
+			}
+			_ = json.NewEncoder(w).Encode(resps)
+			return
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(handle(req))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestClient_SendUserOperationDryRun(t *testing.T) {
+	server, calls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_chainId": func([]json.RawMessage) (interface{}, error) { return "0x1", nil },
+	})
+	client, err := Init(server.URL, nil)
+	require.NoError(t, err)
+
+	builder := NewUserOperationBuilder()
+	builder.SetCallData("0x1234")
+
+	var built *IUserOperation
+	res, err := client.SendUserOperation(builder, &ISendUserOperationOpts{
+		DryRun:  true,
+		OnBuild: func(op *IUserOperation) { built = op },
+	})
+	require.NoError(t, err)
+
+	expected, err := GetUserOpHash(built, common.HexToAddress(constants.ENTRY_POINT), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, hexutil.Encode(expected), res.UserOpHash)
+	assert.Equal(t, "0x1234", built.CallData)
+	assert.Equal(t, "0x", builder.GetCallData(), "builder should be reset after sending")
+	assert.Equal(t, []string{"eth_chainId"}, *calls)
+
+	event, err := res.Wait()
+	assert.NoError(t, err)
+	assert.Nil(t, event)
+}
+
+func TestClient_SendUserOperationDryRunVersion(t *testing.T) {
+	entryPoint := common.HexToAddress("0x00000000000000000000000000000000000e4337")
+	for version, hash := range map[EntryPointVersion]func(*IUserOperation, common.Address, *big.Int) ([]byte, error){
+		EntryPointV07: GetUserOpHashV07,
+		EntryPointV08: GetUserOpHashV08,
+	} {
+		t.Run(string(version), func(t *testing.T) {
+			server, _ := newTestRPCServer(t, map[string]rpcHandler{
+				"eth_chainId": func([]json.RawMessage) (interface{}, error) { return "0x1", nil },
+			})
+			client, err := Init(server.URL, &IClientOpts{EntryPoint: entryPoint, EntryPointVersion: version})
+			require.NoError(t, err)
+
+			builder := NewUserOperationBuilder()
+			builder.SetCallData("0x1234").SetEntryPointVersion(EntryPointV06)
+			builder.UseMiddleware(func(ctx *IUserOperationMiddlewareCtx) error {
+				signed, err := ctx.GetUserOpHash()
+				if err != nil {
+					return err
+				}
+				ctx.Op.Signature = hexutil.Encode(signed)
+				return nil
+			})
+
+			var built *IUserOperation
+			res, err := client.SendUserOperation(builder, &ISendUserOperationOpts{
+				DryRun:  true,
+				OnBuild: func(op *IUserOperation) { built = op },
+			})
+			require.NoError(t, err)
+
+			expected, err := hash(built, entryPoint, big.NewInt(1))
+			require.NoError(t, err)
+			assert.Equal(t, hexutil.Encode(expected), built.Signature, "middleware signs the hash of the client version")
+			assert.Equal(t, hexutil.Encode(expected), res.UserOpHash)
+			assert.Equal(t, EntryPointV06, builder.GetEntryPointVersion(), "the version of the builder is restored")
+		})
+	}
+}
+
+func TestClient_SendUserOperation(t *testing.T) {
+	userOpHash := "0x46defb203cb9d4d91e5d5c1648d75f7831dc4f1a944d51a0672d0bba30cc4811"
+	sender := common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72")
+	txHash := common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+	eventTopic := crypto.Keccak256Hash([]byte("UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)"))
+
+	data, err := utils.EncodeABI(
+		[]string{"uint256", "bool", "uint256", "uint256"},
+		[]interface{}{big.NewInt(7), true, big.NewInt(21000000000000), big.NewInt(140000)},
+	)
+	require.NoError(t, err)
+
+	server, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_chainId": func([]json.RawMessage) (interface{}, error) { return "0x1", nil },
+		"eth_sendUserOperation": func(params []json.RawMessage) (interface{}, error) {
+			var op map[string]string
+			var entryPoint common.Address
+			if err := decodeParams(params, &op, &entryPoint); err != nil {
+				return nil, err
+			}
+			assert.Equal(t, "0x1234", op["callData"])
+			assert.Equal(t, common.HexToAddress(constants.ENTRY_POINT), entryPoint)
+			return userOpHash, nil
+		},
+		"eth_blockNumber": func([]json.RawMessage) (interface{}, error) { return "0x200", nil },
+		"eth_getLogs": func(params []json.RawMessage) (interface{}, error) {
+			var query struct {
+				FromBlock string          `json:"fromBlock"`
+				Topics    [][]common.Hash `json:"topics"`
+			}
+			if err := decodeParams(params, &query); err != nil {
+				return nil, err
+			}
+			assert.Equal(t, "0x200", query.FromBlock, "logs are searched from the block at send time")
+			assert.Equal(t, [][]common.Hash{{eventTopic}, {common.HexToHash(userOpHash)}}, query.Topics)
+			return []map[string]interface{}{{
+				"address": constants.ENTRY_POINT,
+				"topics": []common.Hash{
+					eventTopic,
+					common.HexToHash(userOpHash),
+					common.BytesToHash(sender.Bytes()),
+					{},
+				},
+				"data":             hexutil.Encode(data),
+				"blockNumber":      "0x1ff",
+				"blockHash":        common.Hash{}.Hex(),
+				"transactionHash":  txHash.Hex(),
+				"transactionIndex": "0x0",
+				"logIndex":         "0x0",
+				"removed":          false,
+			}}, nil
+		},
+	})
+	client, err := Init(server.URL, nil)
+	require.NoError(t, err)
+	client.waitTimeout = time.Second
+
+	builder := NewUserOperationBuilder()
+	builder.SetCallData("0x1234")
+
+	res, err := client.SendUserOperation(builder, nil)
+	require.NoError(t, err)
+	assert.Equal(t, userOpHash, res.UserOpHash)
+	assert.Equal(t, uint64(0x200), res.BlockNumber)
+
+	event, err := res.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, &FilterEvent{
+		UserOpHash:      common.HexToHash(userOpHash),
+		Sender:          sender,
+		Paymaster:       common.Address{},
+		Nonce:           big.NewInt(7),
+		Success:         true,
+		ActualGasCost:   big.NewInt(21000000000000),
+		ActualGasUsed:   big.NewInt(140000),
+		TransactionHash: txHash,
+		BlockNumber:     0x1ff,
+	}, event)
+}
+
+func TestClient_WaitTimeout(t *testing.T) {
+	server, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_chainId": func([]json.RawMessage) (interface{}, error) { return "0x1", nil },
+		"eth_getLogs": func([]json.RawMessage) (interface{}, error) { return []interface{}{}, nil },
+	})
+	client, err := Init(server.URL, nil)
+	require.NoError(t, err)
+	client.waitTimeout = 50 * time.Millisecond
+	client.waitInterval = 10 * time.Millisecond
+
+	_, err = client.wait(context.Background(), "0x01", 0x10)
+	assert.Error(t, err)
+}
+
+func TestInit_ClientOpts(t *testing.T) {
+	entryPoint := common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032")
+	node, nodeCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_chainId":     func([]json.RawMessage) (interface{}, error) { return "0x89", nil },
+		"eth_blockNumber": func([]json.RawMessage) (interface{}, error) { return "0x10", nil },
+	})
+	bundler, bundlerCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_sendUserOperation": func(params []json.RawMessage) (interface{}, error) {
+			var target common.Address
+			if err := decodeParams(params, nil, &target); err != nil {
+				return nil, err
+			}
+			assert.Equal(t, entryPoint, target)
+			return "0x01", nil
+		},
+	})
+
+	client, err := Init(node.URL, &IClientOpts{EntryPoint: entryPoint, OverrideBundlerRpc: bundler.URL})
+	require.NoError(t, err)
+	assert.Equal(t, int64(137), client.chainId.Int64())
+
+	_, err = client.SendUserOperation(NewUserOperationBuilder(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eth_chainId", "eth_blockNumber"}, *nodeCalls)
+	assert.Equal(t, []string{"eth_sendUserOperation"}, *bundlerCalls)
+}
+
+// pipeStreamChannel is a StreamChannel over one end of a net.Pipe.
+type pipeStreamChannel struct {
+	conn net.Conn
+}
+
+func (c *pipeStreamChannel) Send(msg string) {
+	_, _ = c.conn.Write([]byte(msg))
+}
+
+func (c *pipeStreamChannel) Receive() string {
+	buf := make([]byte, 4096)
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+type testEthService struct{}
+
+func (s *testEthService) ChainId() hexutil.Uint64 {
+	return 10
+}
+
+func TestInit_SocketConnector(t *testing.T) {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", &testEthService{}))
+	t.Cleanup(server.Stop)
+
+	clientConn, serverConn := net.Pipe()
+	go server.ServeCodec(rpc.NewCodec(serverConn), 0)
+
+	client, err := Init("", &IClientOpts{
+		SocketConnector: func() StreamChannel { return &pipeStreamChannel{conn: clientConn} },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), client.chainId.Int64())
+}
+
+// testHangService answers hang only once release is closed.
+type testHangService struct {
+	release chan struct{}
+}
+
+func (s *testHangService) Hang() {
+	<-s.release
+}
+
+func TestInit_SocketConnectorTimeout(t *testing.T) {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", &testEthService{}))
+	hang := &testHangService{release: make(chan struct{})}
+	require.NoError(t, server.RegisterName("test", hang))
+	t.Cleanup(server.Stop)
+	t.Cleanup(func() { close(hang.release) })
+
+	clientConn, serverConn := net.Pipe()
+	go server.ServeCodec(rpc.NewCodec(serverConn), 0)
+
+	client, err := Init("", &IClientOpts{
+		SocketConnector: func() StreamChannel { return &pipeStreamChannel{conn: clientConn} },
+		RpcOpts:         &IEndpointOpts{Timeout: 50 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = client.provider.CallContext(ctx, nil, "test_hang")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "RpcOpts.Timeout bounds calls over the stream channel")
+	assert.NoError(t, ctx.Err())
+}
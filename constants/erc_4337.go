@@ -4,10 +4,16 @@ type ERC4337 struct{}
 
 const (
 	ENTRY_POINT              = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+	ENTRY_POINT_V07          = "0x0000000071727De22E5E9d8BAf0edAc6f37da032"
+	ENTRY_POINT_V08          = "0x4337084D9E255Ff0702461CF8895CE9E3b5Ff108"
 	SIMPLE_ACCOUNT_FACTORY   = "0x9406Cc6185a346906296840746125a0E44976454"
 	ETHERSPOT_WALLET_FACTORY = "0x7f6d8F107fE8551160BD5351d5F1514A6aD5d40E"
 )
 
+// EIP7702_INITCODE_MARKER is the initCode prefix, 0x7702 padded to 20 bytes,
+// that marks an EntryPoint v0.8 op from an account delegated with EIP-7702.
+const EIP7702_INITCODE_MARKER = "0x7702000000000000000000000000000000000000"
+
 func NewERC4337() *ERC4337 {
 	return &ERC4337{}
 }
@@ -8,6 +8,8 @@ const (
 	ENABLE = "0x00000002"
 )
 
+const KERNEL_ECDSA_FACTORY = "0xf7d5E0c8bDC24807c8793507a2aF586514f4c46e"
+
 func NewKernelModes() *KernelModes {
 	return &KernelModes{}
 }
@@ -1,5 +1,13 @@
 package constants
 
+// Safe 1.4.1 and Safe4337Module v0.2.0 deployments (EntryPoint v0.6)
+const (
+	SAFE_PROXY_FACTORY   = "0x4e1DCf7AD4e460CfD30791CCC4F9c8a4f820ec67"
+	SAFE_SINGLETON       = "0x41675C099F32341bf84BFc5382aF534df5C7461a"
+	SAFE_4337_MODULE     = "0xa581c4A4DB7175302464fF3C06380BC3270b4037"
+	SAFE_ADD_MODULES_LIB = "0x8EcD4ec46D4D2a6B64fE960B3D64e8B94B2234eb"
+)
+
 // ISafeConstant defines an interface that requires a GetMultiSend method
 type ISafeConstant interface {
 	GetMultiSend() map[string]string
@@ -1,9 +1,14 @@
 package userop
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop/typechain"
+	"github.com/withsilasogar/userop/utils"
 )
 
 // UserOperationMiddlewareCtx implements IUserOperationMiddlewareCtx
@@ -21,3 +26,148 @@ func NewUserOperationMiddlewareCtx(op *IUserOperation, entryPoint common.Address
 		ChainId:    chainId,
 	}
 }
+
+// GetUserOpHash returns the hash of the user operation.
+func (ctx *UserOperationMiddlewareCtx) GetUserOpHash() ([]byte, error) {
+	return GetUserOpHash(ctx.Op, ctx.EntryPoint, ctx.ChainId)
+}
+
+// GetUserOpHash returns the hash of the user operation for the EntryPoint version of ctx.
+func (ctx *IUserOperationMiddlewareCtx) GetUserOpHash() ([]byte, error) {
+	return getUserOpHashForVersion(ctx.Op, ctx.EntryPoint, ctx.ChainID, ctx.Version())
+}
+
+// Version returns the EntryPoint version the op is built for, falling back
+// to the version of ctx.EntryPoint when none was set.
+func (ctx *IUserOperationMiddlewareCtx) Version() EntryPointVersion {
+	return resolveEntryPointVersion(ctx.EntryPointVersion, ctx.EntryPoint)
+}
+
+// OpToJSON converts the op to the JSON-like map that bundlers and paymasters
+// for the EntryPoint version of ctx expect.
+func (ctx *IUserOperationMiddlewareCtx) OpToJSON() (map[string]interface{}, error) {
+	return OpToJSON(ctx.Op, ctx.Version())
+}
+
+// GetUserOpHash computes the userOpHash exactly as EntryPoint v0.6
+// getUserOpHash does: keccak256(abi.encode(keccak256(pack(op)), entryPoint, chainId)).
+func GetUserOpHash(op *IUserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error) {
+	if chainID == nil {
+		return nil, fmt.Errorf("chain ID is required to compute the userOpHash")
+	}
+
+	packed, err := packUserOp(op)
+	if err != nil {
+		return nil, err
+	}
+	return hashUserOp(packed, entryPoint, chainID)
+}
+
+// hashUserOp binds the packed op to entryPoint and chainID, as v0.6 and v0.7 both do.
+func hashUserOp(packed []byte, entryPoint common.Address, chainID *big.Int) ([]byte, error) {
+	encoded, err := utils.EncodeABI(
+		[]string{"bytes32", "address", "uint256"},
+		[]interface{}{crypto.Keccak256Hash(packed), entryPoint, chainID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode userOpHash: %w", err)
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// getUserOpHashForVersion computes the userOpHash with the algorithm of version.
+func getUserOpHashForVersion(op *IUserOperation, entryPoint common.Address, chainID *big.Int, version EntryPointVersion) ([]byte, error) {
+	switch version {
+	case EntryPointV07:
+		return GetUserOpHashV07(op, entryPoint, chainID)
+	case EntryPointV08:
+		return GetUserOpHashV08(op, entryPoint, chainID)
+	default:
+		return GetUserOpHash(op, entryPoint, chainID)
+	}
+}
+
+// OpToJSON converts op to the JSON-like map that bundlers for version expect.
+func OpToJSON(op *IUserOperation, version EntryPointVersion) (map[string]interface{}, error) {
+	switch version {
+	case EntryPointV07, EntryPointV08:
+		v07, err := op.ToV07()
+		if err != nil {
+			return nil, err
+		}
+		return v07.ToJSON(), nil
+	default:
+		return op.ToJSON(), nil
+	}
+}
+
+// ToEntryPointUserOperation converts op into the struct used by the EntryPoint binding.
+func (op *IUserOperation) ToEntryPointUserOperation() (typechain.UserOperation, error) {
+	initCode, err := hexutil.Decode(op.InitCode)
+	if err != nil {
+		return typechain.UserOperation{}, fmt.Errorf("invalid initCode: %w", err)
+	}
+	callData, err := hexutil.Decode(op.CallData)
+	if err != nil {
+		return typechain.UserOperation{}, fmt.Errorf("invalid callData: %w", err)
+	}
+	paymasterAndData, err := hexutil.Decode(op.PaymasterAndData)
+	if err != nil {
+		return typechain.UserOperation{}, fmt.Errorf("invalid paymasterAndData: %w", err)
+	}
+	signature, err := hexutil.Decode(op.Signature)
+	if err != nil {
+		return typechain.UserOperation{}, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return typechain.UserOperation{
+		Sender:               op.Sender,
+		Nonce:                op.Nonce,
+		InitCode:             initCode,
+		CallData:             callData,
+		CallGasLimit:         op.CallGasLimit,
+		VerificationGasLimit: op.VerificationGasLimit,
+		PreVerificationGas:   op.PreVerificationGas,
+		MaxFeePerGas:         op.MaxFeePerGas,
+		MaxPriorityFeePerGas: op.MaxPriorityFeePerGas,
+		PaymasterAndData:     paymasterAndData,
+		Signature:            signature,
+	}, nil
+}
+
+// packUserOp ABI-encodes every field of op except the signature, with the
+// dynamic byte fields replaced by their keccak256 hash.
+func packUserOp(op *IUserOperation) ([]byte, error) {
+	initCode, err := hexutil.Decode(op.InitCode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid initCode: %w", err)
+	}
+	callData, err := hexutil.Decode(op.CallData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callData: %w", err)
+	}
+	paymasterAndData, err := hexutil.Decode(op.PaymasterAndData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid paymasterAndData: %w", err)
+	}
+
+	packed, err := utils.EncodeABI(
+		[]string{"address", "uint256", "bytes32", "bytes32", "uint256", "uint256", "uint256", "uint256", "uint256", "bytes32"},
+		[]interface{}{
+			op.Sender,
+			op.Nonce,
+			crypto.Keccak256Hash(initCode),
+			crypto.Keccak256Hash(callData),
+			op.CallGasLimit,
+			op.VerificationGasLimit,
+			op.PreVerificationGas,
+			op.MaxFeePerGas,
+			op.MaxPriorityFeePerGas,
+			crypto.Keccak256Hash(paymasterAndData),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack user operation: %w", err)
+	}
+	return packed, nil
+}
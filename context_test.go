@@ -0,0 +1,92 @@
+package userop
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/withsilasogar/userop/constants"
+)
+
+func TestGetUserOpHash(t *testing.T) {
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT)
+
+	tests := []struct {
+		name     string
+		op       *IUserOperation
+		chainID  *big.Int
+		expected string
+	}{
+		{
+			name:     "default op",
+			op:       NewDefaultUserOperation(),
+			chainID:  big.NewInt(1),
+			expected: "0xdf9d96620522abe0b410b4ee1082ab5d451c1ff9dc321e7e029307a0067bce21",
+		},
+		{
+			name: "simple account deployment with paymaster",
+			op: &IUserOperation{
+				Sender:               common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72"),
+				Nonce:                big.NewInt(7),
+				InitCode:             "0x9406cc6185a346906296840746125a0e449764545fbfb9cf0000000000000000000000008ba1f109551bd432803012645ac136ddd64dba720000000000000000000000000000000000000000000000000000000000000000",
+				CallData:             "0xb61d27f6000000000000000000000000000000000000000000000000000000000000dead00000000000000000000000000000000000000000000000000000000000003e800000000000000000000000000000000000000000000000000000000000000600000000000000000000000000000000000000000000000000000000000000000",
+				CallGasLimit:         big.NewInt(120000),
+				VerificationGasLimit: big.NewInt(450000),
+				PreVerificationGas:   big.NewInt(48000),
+				MaxFeePerGas:         big.NewInt(30000000000),
+				MaxPriorityFeePerGas: big.NewInt(1500000000),
+				PaymasterAndData:     "0xe93eca6595fe94091dc1af46aac2a8b5d7990770",
+				Signature:            "0x",
+			},
+			chainID:  big.NewInt(137),
+			expected: "0x46defb203cb9d4d91e5d5c1648d75f7831dc4f1a944d51a0672d0bba30cc4811",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &IUserOperationMiddlewareCtx{Op: tt.op, EntryPoint: entryPoint, ChainID: tt.chainID}
+
+			hash, err := ctx.GetUserOpHash()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, hexutil.Encode(hash))
+
+			// The signature is not part of the hash.
+			tt.op.Signature = "0xdeadbeef"
+			signed, err := ctx.GetUserOpHash()
+			assert.NoError(t, err)
+			assert.Equal(t, hash, signed)
+		})
+	}
+}
+
+func TestGetUserOpHash_Errors(t *testing.T) {
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT)
+
+	_, err := GetUserOpHash(NewDefaultUserOperation(), entryPoint, nil)
+	assert.Error(t, err)
+
+	op := NewDefaultUserOperation()
+	op.CallData = "not hex"
+	_, err = GetUserOpHash(op, entryPoint, big.NewInt(1))
+	assert.Error(t, err)
+}
+
+func TestToEntryPointUserOperation(t *testing.T) {
+	op := NewDefaultUserOperation()
+	op.CallData = "0x1234"
+	op.Signature = "0xdeadbeef"
+
+	converted, err := op.ToEntryPointUserOperation()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x12, 0x34}, converted.CallData)
+	assert.Equal(t, []byte{0xde, 0xad, 0xbe, 0xef}, converted.Signature)
+	assert.Equal(t, []byte{}, converted.InitCode)
+	assert.Equal(t, op.VerificationGasLimit, converted.VerificationGasLimit)
+
+	op.Signature = "0xzz"
+	_, err = op.ToEntryPointUserOperation()
+	assert.Error(t, err)
+}
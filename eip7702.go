@@ -0,0 +1,123 @@
+package userop
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/withsilasogar/userop/constants"
+)
+
+// eip7702AuthMagic prefixes the RLP encoded authorization before hashing.
+const eip7702AuthMagic = 0x05
+
+// Eip7702Auth is a signed EIP-7702 authorization delegating the code of the
+// signing EOA to Address. EntryPoint v0.8 bundlers include it in the
+// transaction that carries the op.
+type Eip7702Auth struct {
+	ChainID *big.Int
+	Address common.Address
+	Nonce   uint64
+	YParity uint8
+	R       *big.Int
+	S       *big.Int
+}
+
+// SignEip7702Auth signs an authorization delegating the EOA of signer to
+// delegate. nonce is the current transaction nonce of the EOA, and a chainID
+// of zero makes the authorization valid on every chain.
+func SignEip7702Auth(signer *ecdsa.PrivateKey, chainID *big.Int, delegate common.Address, nonce uint64) (*Eip7702Auth, error) {
+	auth := &Eip7702Auth{
+		ChainID: copyBigInt(chainID),
+		Address: delegate,
+		Nonce:   nonce,
+	}
+	hash, err := auth.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(hash, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	auth.R = new(big.Int).SetBytes(signature[:32])
+	auth.S = new(big.Int).SetBytes(signature[32:64])
+	auth.YParity = signature[crypto.RecoveryIDOffset]
+	return auth, nil
+}
+
+// SigHash returns the hash signed by the authority:
+// keccak256(0x05 || rlp([chainId, address, nonce])).
+func (a *Eip7702Auth) SigHash() ([]byte, error) {
+	encoded, err := rlp.EncodeToBytes([]interface{}{copyBigInt(a.ChainID), a.Address, a.Nonce})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode authorization: %w", err)
+	}
+	return crypto.Keccak256([]byte{eip7702AuthMagic}, encoded), nil
+}
+
+// Authority recovers the address of the EOA that signed the authorization.
+func (a *Eip7702Auth) Authority() (common.Address, error) {
+	if a.R == nil || a.S == nil || a.YParity > 1 {
+		return common.Address{}, fmt.Errorf("authorization is not signed")
+	}
+	hash, err := a.SigHash()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	signature := make([]byte, crypto.SignatureLength)
+	a.R.FillBytes(signature[:32])
+	a.S.FillBytes(signature[32:64])
+	signature[crypto.RecoveryIDOffset] = a.YParity
+	pub, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Copy returns a deep copy of the authorization.
+func (a *Eip7702Auth) Copy() *Eip7702Auth {
+	if a == nil {
+		return nil
+	}
+	return &Eip7702Auth{
+		ChainID: copyBigInt(a.ChainID),
+		Address: a.Address,
+		Nonce:   a.Nonce,
+		YParity: a.YParity,
+		R:       copyBigInt(a.R),
+		S:       copyBigInt(a.S),
+	}
+}
+
+// ToJSON converts the authorization to the eip7702Auth object of the bundler RPC.
+func (a *Eip7702Auth) ToJSON() map[string]interface{} {
+	return map[string]interface{}{
+		"chainId": "0x" + copyBigInt(a.ChainID).Text(16),
+		"address": a.Address.Hex(),
+		"nonce":   hexutil.EncodeUint64(a.Nonce),
+		"yParity": hexutil.EncodeUint64(uint64(a.YParity)),
+		"r":       "0x" + copyBigInt(a.R).Text(16),
+		"s":       "0x" + copyBigInt(a.S).Text(16),
+	}
+}
+
+// isEip7702InitCode reports whether initCode starts with the EIP-7702
+// marker. Like the EntryPoint, an initCode shorter than 20 bytes is compared
+// as if it were padded with zeros.
+func isEip7702InitCode(initCode []byte) bool {
+	if len(initCode) < 2 {
+		return false
+	}
+	prefix := make([]byte, common.AddressLength)
+	copy(prefix, initCode)
+	return bytes.Equal(prefix, common.HexToAddress(constants.EIP7702_INITCODE_MARKER).Bytes())
+}
@@ -0,0 +1,240 @@
+package userop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// unhealthyBackoff is how long an upstream that failed is tried only after
+// the healthy upstreams of its endpoint.
+const unhealthyBackoff = 30 * time.Second
+
+// defaultFailoverTimeout bounds each attempt of an endpoint with several URLs
+// and no timeout of its own, so that a hung URL cannot stall the failover.
+var defaultFailoverTimeout = 30 * time.Second
+
+// endpoint is a named upstream with one or more URLs. Calls go to the first
+// healthy URL and fail over to the next one when it cannot be reached.
+type endpoint struct {
+	upstreams []*upstream
+	timeout   time.Duration
+}
+
+// upstream is one connected URL of an endpoint and its health.
+type upstream struct {
+	url    string
+	client *rpc.Client
+
+	mu             sync.Mutex
+	failures       int
+	lastError      error
+	unhealthyUntil time.Time
+}
+
+// dialEndpoint connects to url over HTTP(S), WebSocket, or IPC when url is a
+// filesystem path. WebSocket and IPC clients redial on the next request after
+// the socket drops, but subscriptions are not restored. Headers and the HTTP
+// client of opts only apply to HTTP and WebSocket endpoints.
+func dialEndpoint(ctx context.Context, url string, opts *IEndpointOpts) (*rpc.Client, error) {
+	if opts == nil {
+		return rpc.DialContext(ctx, url)
+	}
+
+	var options []rpc.ClientOption
+	if len(opts.Headers) > 0 {
+		header := make(http.Header, len(opts.Headers))
+		for key, value := range opts.Headers {
+			header.Set(key, value)
+		}
+		options = append(options, rpc.WithHeaders(header))
+	}
+	if opts.HeaderProvider != nil {
+		options = append(options, rpc.WithHTTPAuth(opts.HeaderProvider))
+	}
+	if opts.HTTPClient != nil {
+		options = append(options, rpc.WithHTTPClient(opts.HTTPClient))
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	return rpc.DialOptions(ctx, url, options...)
+}
+
+// newEndpoint dials every url with opts. Nothing is kept open if one fails.
+func newEndpoint(urls []string, opts *IEndpointOpts) (*endpoint, error) {
+	e := &endpoint{}
+	if opts != nil {
+		e.timeout = opts.Timeout
+	}
+	for _, url := range urls {
+		client, err := dialEndpoint(context.Background(), url, opts)
+		if err != nil {
+			e.close()
+			return nil, err
+		}
+		e.upstreams = append(e.upstreams, &upstream{url: url, client: client})
+	}
+	return e, nil
+}
+
+// close closes the clients of every upstream.
+func (e *endpoint) close() {
+	for _, u := range e.upstreams {
+		u.client.Close()
+	}
+}
+
+// withTimeout bounds a single attempt by the endpoint timeout. A deadline of
+// ctx that is sooner still applies.
+func (e *endpoint) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := e.timeout
+	if timeout <= 0 && len(e.upstreams) > 1 {
+		timeout = defaultFailoverTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ordered returns the healthy upstreams in their configured order, followed
+// by the unhealthy ones as a last resort.
+func (e *endpoint) ordered() []*upstream {
+	if len(e.upstreams) == 1 {
+		return e.upstreams
+	}
+	now := time.Now()
+	healthy := make([]*upstream, 0, len(e.upstreams))
+	var unhealthy []*upstream
+	for _, u := range e.upstreams {
+		if u.healthy(now) {
+			healthy = append(healthy, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// call runs fn against each upstream in order until one of them answers,
+// which includes answering with a JSON-RPC error.
+func (e *endpoint) call(ctx context.Context, fn func(ctx context.Context, client *rpc.Client) error) error {
+	return e.failover(ctx, true, fn)
+}
+
+// send is call for a request that must not be submitted twice: an attempt
+// that timed out may still have been received, so it is not failed over.
+func (e *endpoint) send(ctx context.Context, fn func(ctx context.Context, client *rpc.Client) error) error {
+	return e.failover(ctx, false, fn)
+}
+
+// failover runs fn against each upstream in order until one of them answers.
+// Timed-out attempts are only retried on the next upstream if retryTimeouts.
+func (e *endpoint) failover(ctx context.Context, retryTimeouts bool, fn func(ctx context.Context, client *rpc.Client) error) error {
+	var errs []error
+	for _, u := range e.ordered() {
+		callCtx, cancel := e.withTimeout(ctx)
+		err := fn(callCtx, u.client)
+		cancel()
+		u.record(err)
+		if !isUnreachable(err) || ctx.Err() != nil {
+			return err
+		}
+		if !retryTimeouts && errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// broadcast sends the call to every upstream at once and decodes the first
+// successful response into result. The other calls keep running until they
+// finish or ctx is done.
+func (e *endpoint) broadcast(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	type reply struct {
+		raw json.RawMessage
+		err error
+	}
+	replies := make(chan reply, len(e.upstreams))
+	for _, u := range e.upstreams {
+		go func(u *upstream) {
+			callCtx, cancel := e.withTimeout(ctx)
+			defer cancel()
+			var raw json.RawMessage
+			err := u.client.CallContext(callCtx, &raw, method, args...)
+			u.record(err)
+			replies <- reply{raw: raw, err: err}
+		}(u)
+	}
+
+	var errs []error
+	for range e.upstreams {
+		r := <-replies
+		if r.err == nil {
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(r.raw, result)
+		}
+		errs = append(errs, r.err)
+	}
+	return errors.Join(errs...)
+}
+
+// health returns the health of every upstream of the endpoint.
+func (e *endpoint) health() []IEndpointHealth {
+	now := time.Now()
+	health := make([]IEndpointHealth, len(e.upstreams))
+	for i, u := range e.upstreams {
+		u.mu.Lock()
+		health[i] = IEndpointHealth{
+			URL:       u.url,
+			Healthy:   now.After(u.unhealthyUntil),
+			Failures:  u.failures,
+			LastError: u.lastError,
+		}
+		u.mu.Unlock()
+	}
+	return health
+}
+
+// healthy reports whether the upstream is outside its backoff after a failure.
+func (u *upstream) healthy(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return now.After(u.unhealthyUntil)
+}
+
+// record updates the health of the upstream with the outcome of a call.
+func (u *upstream) record(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !isUnreachable(err) {
+		u.failures = 0
+		u.unhealthyUntil = time.Time{}
+		return
+	}
+	u.failures++
+	u.lastError = err
+	u.unhealthyUntil = time.Now().Add(unhealthyBackoff)
+}
+
+// isUnreachable reports whether err means the upstream could not serve the
+// call, as opposed to a JSON-RPC error response that another upstream would
+// most likely return as well.
+func isUnreachable(err error) bool {
+	var rpcErr rpc.Error
+	return err != nil && !errors.As(err, &rpcErr)
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // UserOperationEventFilter defines the filter options for the UserOperation event
@@ -27,7 +29,13 @@ func NewUserOperationEventFilter(contract common.Address, event string, fromBloc
 		Topics:    [][]common.Hash{},
 	}
 
-	// Add the user operation hash to the topics if it's not empty
+	// The first topic is always the event itself, followed by the indexed
+	// user operation hash if one is given
+	if event != "" {
+		filter.Topics = append(filter.Topics, []common.Hash{eventTopic(event)})
+	} else if userOpHash != "" {
+		filter.Topics = append(filter.Topics, nil)
+	}
 	if userOpHash != "" {
 		filter.Topics = append(filter.Topics, []common.Hash{common.HexToHash(userOpHash)})
 	}
@@ -35,6 +43,15 @@ func NewUserOperationEventFilter(contract common.Address, event string, fromBloc
 	return filter
 }
 
+// eventTopic returns the topic for an event given either its topic hash or its
+// signature, e.g. "UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)".
+func eventTopic(event string) common.Hash {
+	if topic, err := hexutil.Decode(event); err == nil && len(topic) == common.HashLength {
+		return common.BytesToHash(topic)
+	}
+	return crypto.Keccak256Hash([]byte(event))
+}
+
 // ToFilterQuery converts UserOperationEventFilter to the ethereum FilterQuery object
 func (f *UserOperationEventFilter) ToFilterQuery() ethereum.FilterQuery {
 	return ethereum.FilterQuery{
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 )
 
-// GasEstimate represents the gas estimate for transactions
+// GasEstimate represents the gas estimate for transactions.
+// PaymasterVerificationGasLimit is only returned for EntryPoint v0.7 and later.
 type GasEstimate struct {
-	VerificationGasLimit *string `json:"verificationGasLimit"`
-	PreVerificationGas   string  `json:"preVerificationGas"`
-	CallGasLimit         string  `json:"callGasLimit"`
-	VerificationGas      string  `json:"verificationGas"`
+	VerificationGasLimit          *string `json:"verificationGasLimit"`
+	PreVerificationGas            string  `json:"preVerificationGas"`
+	CallGasLimit                  string  `json:"callGasLimit"`
+	VerificationGas               string  `json:"verificationGas"`
+	PaymasterVerificationGasLimit *string `json:"paymasterVerificationGasLimit,omitempty"`
 }
 
 // NewGasEstimate creates a new instance of GasEstimate
@@ -22,6 +24,15 @@ func NewGasEstimate(verificationGasLimit *string, preVerificationGas, callGasLim
 	}
 }
 
+// GetVerificationGasLimit returns the verification gas limit, falling back to
+// VerificationGas for bundlers that use the older field name
+func (g *GasEstimate) GetVerificationGasLimit() string {
+	if g.VerificationGasLimit != nil && *g.VerificationGasLimit != "" {
+		return *g.VerificationGasLimit
+	}
+	return g.VerificationGas
+}
+
 // FromJSON unmarshals a JSON object into a GasEstimate instance
 func (g *GasEstimate) FromJSON(data []byte) (*GasEstimate, error) {
 	var gasEstimate GasEstimate
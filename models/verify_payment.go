@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 )
 
-// VerifyingPaymasterResult represents the result of a verifying paymaster operation
+// VerifyingPaymasterResult represents the result of a verifying paymaster operation.
+// EntryPoint v0.7 paymasters return the split paymaster fields instead of PaymasterAndData.
 type VerifyingPaymasterResult struct {
-	PaymasterAndData     string `json:"paymasterAndData"`
-	PreVerificationGas   string `json:"preVerificationGas"`
-	VerificationGasLimit string `json:"verificationGasLimit"`
-	CallGasLimit         string `json:"callGasLimit"`
+	PaymasterAndData              string `json:"paymasterAndData"`
+	PreVerificationGas            string `json:"preVerificationGas"`
+	VerificationGasLimit          string `json:"verificationGasLimit"`
+	CallGasLimit                  string `json:"callGasLimit"`
+	Paymaster                     string `json:"paymaster,omitempty"`
+	PaymasterVerificationGasLimit string `json:"paymasterVerificationGasLimit,omitempty"`
+	PaymasterPostOpGasLimit       string `json:"paymasterPostOpGasLimit,omitempty"`
+	PaymasterData                 string `json:"paymasterData,omitempty"`
 }
 
 // NewVerifyingPaymasterResult creates a new instance of VerifyingPaymasterResult
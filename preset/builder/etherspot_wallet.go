@@ -1 +1,81 @@
 package preset
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/preset/middleware"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+// EtherspotWallet is a UserOperationBuilder preset for the Etherspot wallet.
+type EtherspotWallet struct {
+	*account
+	proxy abi.ABI
+}
+
+// NewEtherspotWallet creates an EtherspotWallet owned by signer. opts.Salt is
+// used as the factory index of the wallet.
+func NewEtherspotWallet(signer *ecdsa.PrivateKey, rpcUrl string, opts *userop.IPresetBuilderOpts) (*EtherspotWallet, error) {
+	opts = withPresetDefaults(opts, constants.ETHERSPOT_WALLET_FACTORY)
+
+	factory, err := abi.JSON(strings.NewReader(typechain.EtherspotWalletFactoryContract))
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := abi.JSON(strings.NewReader(typechain.EtherspotWalletContract))
+	if err != nil {
+		return nil, err
+	}
+
+	owner := crypto.PubkeyToAddress(signer.PublicKey)
+	createAccount, err := factory.Pack("createAccount", owner, opts.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := newAccount(signer, rpcUrl, opts, append(opts.FactoryAddress.Bytes(), createAccount...))
+	if err != nil {
+		return nil, err
+	}
+	w := &EtherspotWallet{account: base, proxy: proxy}
+	w.UseMiddleware(middleware.EOASignature(signer))
+
+	return w, nil
+}
+
+// Execute sets the callData to a single call from the wallet.
+func (w *EtherspotWallet) Execute(call userop.Call) (*EtherspotWallet, error) {
+	callData, err := w.proxy.Pack("execute", call.To, valueOrZero(call.Value), call.Data)
+	if err != nil {
+		return nil, err
+	}
+	w.SetCallData(hexutil.Encode(callData))
+	return w, nil
+}
+
+// ExecuteBatch sets the callData to a batch of calls from the wallet.
+func (w *EtherspotWallet) ExecuteBatch(calls []userop.Call) (*EtherspotWallet, error) {
+	dest := make([]common.Address, len(calls))
+	value := make([]*big.Int, len(calls))
+	data := make([][]byte, len(calls))
+	for i, call := range calls {
+		dest[i] = call.To
+		value[i] = valueOrZero(call.Value)
+		data[i] = call.Data
+	}
+
+	callData, err := w.proxy.Pack("executeBatch", dest, value, data)
+	if err != nil {
+		return nil, err
+	}
+	w.SetCallData(hexutil.Encode(callData))
+	return w, nil
+}
@@ -0,0 +1,67 @@
+package preset
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+func TestEtherspotWallet_BuildOp(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(signer.PublicKey)
+
+	chain := &testChain{
+		sender: common.HexToAddress("0x7c5b3c6a0b3cF4DB4E3ad9d8a4e4f1B6fd1E2A31"),
+		nonce:  big.NewInt(0),
+		code:   "0x",
+	}
+	server := newTestChain(t, chain)
+
+	wallet, err := NewEtherspotWallet(signer, server.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, chain.sender, wallet.GetSender())
+
+	factory, err := abi.JSON(strings.NewReader(typechain.EtherspotWalletFactoryContract))
+	require.NoError(t, err)
+	createAccount, err := factory.Pack("createAccount", owner, big.NewInt(0))
+	require.NoError(t, err)
+	expectedInitCode := append(common.HexToAddress(constants.ETHERSPOT_WALLET_FACTORY).Bytes(), createAccount...)
+	assert.Equal(t, expectedInitCode, chain.initCode)
+
+	calls := []userop.Call{
+		{To: common.HexToAddress("0x02"), Value: big.NewInt(1), Data: []byte{0x01}},
+		{To: common.HexToAddress("0x03")},
+	}
+	_, err = wallet.ExecuteBatch(calls)
+	require.NoError(t, err)
+
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT)
+	op, err := wallet.BuildOp(entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, hexutil.Encode(expectedInitCode), op.InitCode)
+
+	proxy, err := abi.JSON(strings.NewReader(typechain.EtherspotWalletContract))
+	require.NoError(t, err)
+	args, err := proxy.Methods["executeBatch"].Inputs.Unpack(hexutil.MustDecode(op.CallData)[4:])
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{calls[0].To, calls[1].To}, args[0])
+	values := args[1].([]*big.Int)
+	assert.Equal(t, int64(1), values[0].Int64())
+	assert.Equal(t, int64(0), values[1].Int64())
+	assert.Equal(t, [][]byte{{0x01}, {}}, args[2])
+
+	hash, err := userop.GetUserOpHash(op, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, owner, recoverSigner(t, hash, hexutil.MustDecode(op.Signature)))
+}
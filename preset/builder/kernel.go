@@ -0,0 +1,124 @@
+package preset
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/preset/middleware"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+// Kernel is a UserOperationBuilder preset for a Kernel account deployed by
+// the ECDSAKernelFactory. Signatures are prefixed with the Kernel mode,
+// which defaults to constants.SUDO.
+type Kernel struct {
+	*account
+	proxy     abi.ABI
+	multisend *typechain.Multisend // bound on the first ExecuteBatch
+	mode      []byte
+}
+
+// NewKernel creates a Kernel account owned by signer. opts.Salt is used as
+// the factory index of the account.
+func NewKernel(signer *ecdsa.PrivateKey, rpcUrl string, opts *userop.IPresetBuilderOpts) (*Kernel, error) {
+	opts = withPresetDefaults(opts, constants.KERNEL_ECDSA_FACTORY)
+
+	factory, err := typechain.NewECDSAKernelFactory(nil, opts.FactoryAddress.Hex())
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := abi.JSON(strings.NewReader(typechain.KernelContract))
+	if err != nil {
+		return nil, err
+	}
+
+	initCode, err := factory.InitCode(crypto.PubkeyToAddress(signer.PublicKey), opts.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := newAccount(signer, rpcUrl, opts, initCode)
+	if err != nil {
+		return nil, err
+	}
+	k := &Kernel{
+		account: base,
+		proxy:   proxy,
+		mode:    hexutil.MustDecode(constants.SUDO),
+	}
+	k.UseDefaults(map[string]interface{}{"signature": k.dummySignature()}).
+		UseMiddleware(k.signUserOp)
+
+	return k, nil
+}
+
+// SetMode selects the Kernel mode used to prefix signatures, e.g. constants.SUDO.
+// The dummy signature used for gas estimation is prefixed with the new mode,
+// unless a signature was set on the current op.
+func (k *Kernel) SetMode(mode string) (*Kernel, error) {
+	decoded, err := hexutil.Decode(mode)
+	if err != nil || len(decoded) != 4 {
+		return nil, fmt.Errorf("invalid kernel mode %q", mode)
+	}
+
+	signature, previous := k.GetSignature(), k.dummySignature()
+	k.mode = decoded
+	k.UseDefaults(map[string]interface{}{"signature": k.dummySignature()})
+	if signature != previous {
+		k.SetSignature(signature)
+	}
+	return k, nil
+}
+
+// dummySignature returns the signature of a fixed message prefixed with the mode.
+func (k *Kernel) dummySignature() string {
+	return hexutil.Encode(bytes.Join([][]byte{k.mode, k.dummySig}, nil))
+}
+
+// Execute sets the callData to a single call from the account.
+func (k *Kernel) Execute(call userop.Call) (*Kernel, error) {
+	callData, err := k.proxy.Pack("execute", call.To, valueOrZero(call.Value), call.Data, operationCall)
+	if err != nil {
+		return nil, err
+	}
+	k.SetCallData(hexutil.Encode(callData))
+	return k, nil
+}
+
+// ExecuteBatch sets the callData to a DELEGATECALL to MultiSend with calls.
+func (k *Kernel) ExecuteBatch(calls []userop.Call) (*Kernel, error) {
+	if k.multisend == nil {
+		multisend, err := multisendFor(k.provider)
+		if err != nil {
+			return nil, err
+		}
+		k.multisend = multisend
+	}
+	data, err := encodeMultiSend(k.multisend, calls)
+	if err != nil {
+		return nil, err
+	}
+	callData, err := k.proxy.Pack("execute", k.multisend.Address, big.NewInt(0), data, operationDelegateCall)
+	if err != nil {
+		return nil, err
+	}
+	k.SetCallData(hexutil.Encode(callData))
+	return k, nil
+}
+
+// signUserOp signs the userOpHash and prefixes the signature with the mode.
+func (k *Kernel) signUserOp(ctx *userop.IUserOperationMiddlewareCtx) error {
+	if err := middleware.EOASignature(k.signer)(ctx); err != nil {
+		return err
+	}
+	ctx.Op.Signature = hexutil.Encode(bytes.Join([][]byte{k.mode, hexutil.MustDecode(ctx.Op.Signature)}, nil))
+	return nil
+}
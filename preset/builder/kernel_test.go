@@ -0,0 +1,107 @@
+package preset
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+func TestKernel_BuildOp(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(signer.PublicKey)
+
+	chain := &testChain{
+		sender: common.HexToAddress("0x3F2a1c5C4b8D2c5fE0bB8d7aD3D7F2E1c0A9b8c7"),
+		nonce:  big.NewInt(0),
+		code:   "0x",
+	}
+	server := newTestChain(t, chain)
+
+	kernel, err := NewKernel(signer, server.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, chain.sender, kernel.GetSender())
+	assert.Equal(t, common.HexToAddress(constants.KERNEL_ECDSA_FACTORY).Bytes(), chain.initCode[:20])
+
+	calls := []userop.Call{
+		{To: common.HexToAddress("0x02"), Value: big.NewInt(1), Data: []byte{0xaa, 0xbb}},
+		{To: common.HexToAddress("0x03")},
+	}
+	_, err = kernel.ExecuteBatch(calls)
+	require.NoError(t, err)
+
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT)
+	op, err := kernel.BuildOp(entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+
+	proxy, err := abi.JSON(strings.NewReader(typechain.KernelContract))
+	require.NoError(t, err)
+	args, err := proxy.Methods["execute"].Inputs.Unpack(hexutil.MustDecode(op.CallData)[4:])
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress(constants.NewSafe().GetMultiSend()["1"]), args[0])
+	assert.Equal(t, operationDelegateCall, args[3])
+
+	multisend, err := typechain.NewMultisend(common.Address{}, nil, big.NewInt(1))
+	require.NoError(t, err)
+	inner, err := multisend.Abi.Methods["multiSend"].Inputs.Unpack(args[2].([]byte)[4:])
+	require.NoError(t, err)
+	expected := hexutil.MustDecode("0x" +
+		"00" + "0000000000000000000000000000000000000002" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002" + "aabb" +
+		"00" + "0000000000000000000000000000000000000003" +
+		"0000000000000000000000000000000000000000000000000000000000000000" +
+		"0000000000000000000000000000000000000000000000000000000000000000")
+	assert.Equal(t, expected, inner[0])
+
+	signature := hexutil.MustDecode(op.Signature)
+	assert.Equal(t, hexutil.MustDecode(constants.SUDO), signature[:4])
+	hash, err := userop.GetUserOpHash(op, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, owner, recoverSigner(t, hash, signature[4:]))
+
+	kernel.ResetOp()
+	_, err = kernel.SetMode(constants.PLUGIN)
+	require.NoError(t, err)
+	_, err = kernel.ExecuteBatch(calls)
+	require.NoError(t, err)
+	op, err = kernel.BuildOp(entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, hexutil.MustDecode(constants.PLUGIN), hexutil.MustDecode(op.Signature)[:4])
+	require.Len(t, chain.estimated, 2)
+	assert.Equal(t, hexutil.MustDecode(constants.SUDO), hexutil.MustDecode(chain.estimated[0])[:4])
+	assert.Equal(t, hexutil.MustDecode(constants.PLUGIN), hexutil.MustDecode(chain.estimated[1])[:4], "the dummy signature follows the mode")
+
+	_, err = kernel.SetMode("0x01")
+	assert.Error(t, err)
+}
+
+func TestKernel_WithoutMultiSend(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chain := &testChain{
+		sender:  common.HexToAddress("0x3F2a1c5C4b8D2c5fE0bB8d7aD3D7F2E1c0A9b8c7"),
+		nonce:   big.NewInt(0),
+		code:    "0x",
+		chainID: "0xdeadbeef",
+	}
+	server := newTestChain(t, chain)
+
+	account, err := NewKernel(signer, server.URL, nil)
+	require.NoError(t, err, "a chain without MultiSend only rules out batches")
+
+	_, err = account.Execute(userop.Call{To: common.HexToAddress("0x02"), Data: []byte{0xaa}})
+	assert.NoError(t, err)
+	_, err = account.ExecuteBatch([]userop.Call{{To: common.HexToAddress("0x02")}})
+	assert.ErrorContains(t, err, "multisend contract not deployed on network: 3735928559")
+}
@@ -0,0 +1,195 @@
+package preset
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/preset/middleware"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+// Operation types understood by Kernel and Safe accounts.
+const (
+	operationCall         uint8 = 0
+	operationDelegateCall uint8 = 1
+)
+
+// withPresetDefaults returns a copy of opts with every unset field defaulted.
+func withPresetDefaults(opts *userop.IPresetBuilderOpts, factory string) *userop.IPresetBuilderOpts {
+	resolved := userop.IPresetBuilderOpts{}
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.EntryPoint == (common.Address{}) {
+		resolved.EntryPoint = common.HexToAddress(constants.ENTRY_POINT)
+	}
+	if resolved.FactoryAddress == (common.Address{}) {
+		resolved.FactoryAddress = common.HexToAddress(factory)
+	}
+	if resolved.Salt == nil {
+		resolved.Salt = big.NewInt(0)
+	}
+	if resolved.NonceKey == nil {
+		resolved.NonceKey = big.NewInt(0)
+	}
+	return &resolved
+}
+
+// newPresetProvider connects to rpcUrl and binds the EntryPoint from opts.
+func newPresetProvider(rpcUrl string, opts *userop.IPresetBuilderOpts) (*userop.BundlerJsonRpcProvider, *typechain.EntryPoint, error) {
+	providerOpts := &userop.IProviderOpts{
+		EndpointOpts: map[string]*userop.IEndpointOpts{
+			userop.EndpointNode:    opts.RpcOpts,
+			userop.EndpointBundler: opts.BundlerRpcOpts,
+		},
+	}
+	bundlerRpcs := opts.FallbackBundlerRpcs
+	if opts.OverrideBundlerRpc != "" {
+		bundlerRpcs = append([]string{opts.OverrideBundlerRpc}, bundlerRpcs...)
+	}
+	if len(bundlerRpcs) > 0 {
+		providerOpts.FailoverEndpoints = map[string][]string{userop.EndpointBundler: bundlerRpcs}
+	}
+
+	provider, err := userop.NewBundlerJsonRpcProviderWithOpts(rpcUrl, providerOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entryPoint, err := typechain.NewEntryPoint(opts.EntryPoint, provider.Client, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return provider, entryPoint, nil
+}
+
+// account is the part of a preset shared by every account type: the builder
+// and its connection, and the initCode that deploys the account.
+type account struct {
+	*userop.UserOperationBuilder
+	signer     *ecdsa.PrivateKey
+	provider   *userop.BundlerJsonRpcProvider
+	entryPoint *typechain.EntryPoint
+	nonceKey   *big.Int
+	initCode   []byte
+	dummySig   []byte
+}
+
+// newAccount connects to rpcUrl and resolves the counterfactual sender of
+// initCode. The builder defaults to that sender and a dummy EOA signature, and
+// its middleware resolves the nonce and initCode, the gas price, then the
+// paymaster or gas limits. Presets add their signing middleware last.
+func newAccount(signer *ecdsa.PrivateKey, rpcUrl string, opts *userop.IPresetBuilderOpts, initCode []byte) (*account, error) {
+	provider, entryPoint, err := newPresetProvider(rpcUrl, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := entryPoint.GetSenderAddress(context.Background(), initCode)
+	if err != nil {
+		provider.Close()
+		return nil, fmt.Errorf("failed to resolve sender address: %w", err)
+	}
+	dummySignature, err := middleware.SignMessage(signer, crypto.Keccak256(hexutil.MustDecode("0xdead")))
+	if err != nil {
+		provider.Close()
+		return nil, err
+	}
+
+	a := &account{
+		UserOperationBuilder: userop.NewUserOperationBuilder(),
+		signer:               signer,
+		provider:             provider,
+		entryPoint:           entryPoint,
+		nonceKey:             opts.NonceKey,
+		initCode:             initCode,
+		dummySig:             hexutil.MustDecode(dummySignature),
+	}
+	a.UseDefaults(map[string]interface{}{
+		"sender":    sender,
+		"signature": dummySignature,
+	}).UseMiddleware(a.resolveAccount).
+		UseMiddleware(middleware.GetGasPrice(provider))
+	if opts.PaymasterMiddleware != nil {
+		a.UseMiddleware(opts.PaymasterMiddleware)
+	} else {
+		a.UseMiddleware(middleware.EstimateUserOperationGas(provider))
+	}
+	return a, nil
+}
+
+// resolveAccount sets the nonce from the EntryPoint and only includes the
+// initCode while the account has no code yet.
+func (a *account) resolveAccount(ctx *userop.IUserOperationMiddlewareCtx) error {
+	nonce, err := a.entryPoint.GetNonce(context.Background(), ctx.Op.Sender, a.nonceKey)
+	if err != nil {
+		return fmt.Errorf("failed to fetch nonce: %w", err)
+	}
+
+	var code hexutil.Bytes
+	if err := a.provider.CallContext(context.Background(), &code, "eth_getCode", ctx.Op.Sender, "latest"); err != nil {
+		return fmt.Errorf("failed to fetch account code: %w", err)
+	}
+	ctx.Op.Nonce = nonce
+	if len(code) > 0 {
+		ctx.Op.InitCode = "0x"
+	} else {
+		ctx.Op.InitCode = hexutil.Encode(a.initCode)
+	}
+	return nil
+}
+
+func valueOrZero(value *big.Int) *big.Int {
+	if value == nil {
+		return big.NewInt(0)
+	}
+	return value
+}
+
+// chainID fetches the chain ID of the node behind provider.
+func chainID(provider *userop.BundlerJsonRpcProvider) (*big.Int, error) {
+	var id hexutil.Big
+	if err := provider.CallContext(context.Background(), &id, "eth_chainId"); err != nil {
+		return nil, fmt.Errorf("failed to fetch chain ID: %w", err)
+	}
+	return id.ToInt(), nil
+}
+
+// multisendFor binds the Safe MultiSend deployment of the chain behind provider.
+func multisendFor(provider *userop.BundlerJsonRpcProvider) (*typechain.Multisend, error) {
+	chain, err := chainID(provider)
+	if err != nil {
+		return nil, err
+	}
+	return newMultisend(chain)
+}
+
+// newMultisend binds the Safe MultiSend deployment for the given chain.
+func newMultisend(chainID *big.Int) (*typechain.Multisend, error) {
+	address, ok := constants.NewSafe().GetMultiSend()[chainID.String()]
+	if !ok {
+		return nil, fmt.Errorf("multisend contract not deployed on network: %s", chainID)
+	}
+	return typechain.NewMultisend(common.HexToAddress(address), nil, chainID)
+}
+
+// encodeMultiSend packs calls into the MultiSend transactions format:
+// operation (uint8), to (address), value (uint256), data length (uint256), data.
+func encodeMultiSend(multisend *typechain.Multisend, calls []userop.Call) ([]byte, error) {
+	var transactions []byte
+	for _, call := range calls {
+		transactions = append(transactions, operationCall)
+		transactions = append(transactions, call.To.Bytes()...)
+		transactions = append(transactions, common.LeftPadBytes(valueOrZero(call.Value).Bytes(), 32)...)
+		transactions = append(transactions, common.LeftPadBytes(big.NewInt(int64(len(call.Data))).Bytes(), 32)...)
+		transactions = append(transactions, call.Data...)
+	}
+	return multisend.Abi.Pack("multiSend", transactions)
+}
@@ -0,0 +1,132 @@
+package preset
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop/typechain"
+	"github.com/withsilasogar/userop/utils"
+)
+
+// testChain is a fake node that serves the EntryPoint calls used by presets.
+type testChain struct {
+	sender   common.Address
+	nonce    *big.Int
+	code     string
+	initCode []byte
+	// chainID defaults to 0x1.
+	chainID string
+	// estimated holds the signature of each op sent to eth_estimateUserOperationGas.
+	estimated []string
+}
+
+func newTestChain(t *testing.T, chain *testChain) *httptest.Server {
+	t.Helper()
+	entryPoint, err := abi.JSON(strings.NewReader(typechain.EntryPointContract))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_chainId":
+			resp["result"] = "0x1"
+			if chain.chainID != "" {
+				resp["result"] = chain.chainID
+			}
+		case "eth_getBlockByNumber":
+			resp["result"] = map[string]interface{}{"baseFeePerGas": "0x3b9aca00"}
+		case "eth_maxPriorityFeePerGas":
+			resp["result"] = "0x5f5e100"
+		case "eth_estimateUserOperationGas":
+			var op struct {
+				Signature string `json:"signature"`
+			}
+			if !assert.NoError(t, json.Unmarshal(req.Params[0], &op)) {
+				return
+			}
+			chain.estimated = append(chain.estimated, op.Signature)
+			resp["result"] = map[string]interface{}{
+				"preVerificationGas":   "0xb3b0",
+				"verificationGasLimit": "0x186a0",
+				"callGasLimit":         "0x8214",
+			}
+		case "eth_getCode":
+			resp["result"] = chain.code
+		case "eth_call":
+			var call struct {
+				Input hexutil.Bytes `json:"input"`
+			}
+			if !assert.NoError(t, json.Unmarshal(req.Params[0], &call)) {
+				return
+			}
+			method, err := entryPoint.MethodById(call.Input)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			switch method.Name {
+			case "getSenderAddress":
+				args, err := method.Inputs.Unpack(call.Input[4:])
+				if !assert.NoError(t, err) {
+					return
+				}
+				chain.initCode = args[0].([]byte)
+				revert, err := utils.EncodeABI([]string{"address"}, []interface{}{chain.sender})
+				if !assert.NoError(t, err) {
+					return
+				}
+				selector := entryPoint.Errors["SenderAddressResult"].ID
+				resp["error"] = map[string]interface{}{
+					"code":    3,
+					"message": "execution reverted",
+					"data":    hexutil.Encode(append(selector[:4], revert...)),
+				}
+			case "getNonce":
+				nonce, err := method.Outputs.Pack(chain.nonce)
+				if !assert.NoError(t, err) {
+					return
+				}
+				resp["result"] = hexutil.Encode(nonce)
+			default:
+				t.Errorf("unexpected EntryPoint call %s", method.Name)
+			}
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// recoverSigner returns the address that signed hash as an EIP-191 message.
+func recoverSigner(t *testing.T, hash []byte, signature []byte) common.Address {
+	t.Helper()
+	sig := bytes.Clone(signature)
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash(hash), sig)
+	require.NoError(t, err)
+	return crypto.PubkeyToAddress(*pub)
+}
@@ -0,0 +1,206 @@
+package preset
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/typechain"
+	"github.com/withsilasogar/userop/utils"
+)
+
+var (
+	safeDomainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	safeOpTypeHash     = crypto.Keccak256Hash([]byte("SafeOp(address safe,uint256 nonce,bytes initCode,bytes callData,uint256 callGasLimit,uint256 verificationGasLimit,uint256 preVerificationGas,uint256 maxFeePerGas,uint256 maxPriorityFeePerGas,bytes paymasterAndData,uint48 validAfter,uint48 validUntil,address entryPoint)"))
+)
+
+// SafeAccount is a UserOperationBuilder preset for a Safe with the
+// Safe4337Module enabled as both module and fallback handler.
+type SafeAccount struct {
+	*account
+	module    common.Address
+	proxy     abi.ABI
+	multisend *typechain.Multisend // bound on the first ExecuteBatch
+}
+
+// NewSafeAccount creates a single owner Safe for signer. opts.FactoryAddress
+// is the SafeProxyFactory and opts.Salt the proxy salt nonce.
+func NewSafeAccount(signer *ecdsa.PrivateKey, rpcUrl string, opts *userop.IPresetBuilderOpts) (*SafeAccount, error) {
+	opts = withPresetDefaults(opts, constants.SAFE_PROXY_FACTORY)
+
+	proxy, err := abi.JSON(strings.NewReader(typechain.Safe4337ModuleContract))
+	if err != nil {
+		return nil, err
+	}
+
+	module := common.HexToAddress(constants.SAFE_4337_MODULE)
+	initCode, err := safeInitCode(opts.FactoryAddress, crypto.PubkeyToAddress(signer.PublicKey), module, opts.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := newAccount(signer, rpcUrl, opts, initCode)
+	if err != nil {
+		return nil, err
+	}
+	s := &SafeAccount{account: base, module: module, proxy: proxy}
+	s.UseDefaults(map[string]interface{}{
+		"signature": hexutil.Encode(bytes.Join([][]byte{make([]byte, 12), s.dummySig}, nil)),
+	}).UseMiddleware(s.signUserOp)
+
+	return s, nil
+}
+
+// Execute sets the callData to a single call from the Safe.
+func (s *SafeAccount) Execute(call userop.Call) (*SafeAccount, error) {
+	callData, err := s.proxy.Pack("executeUserOp", call.To, valueOrZero(call.Value), call.Data, operationCall)
+	if err != nil {
+		return nil, err
+	}
+	s.SetCallData(hexutil.Encode(callData))
+	return s, nil
+}
+
+// ExecuteBatch sets the callData to a DELEGATECALL to the chain's MultiSend with calls.
+func (s *SafeAccount) ExecuteBatch(calls []userop.Call) (*SafeAccount, error) {
+	if s.multisend == nil {
+		multisend, err := multisendFor(s.provider)
+		if err != nil {
+			return nil, err
+		}
+		s.multisend = multisend
+	}
+	data, err := encodeMultiSend(s.multisend, calls)
+	if err != nil {
+		return nil, err
+	}
+	callData, err := s.proxy.Pack("executeUserOp", s.multisend.Address, big.NewInt(0), data, operationDelegateCall)
+	if err != nil {
+		return nil, err
+	}
+	s.SetCallData(hexutil.Encode(callData))
+	return s, nil
+}
+
+// signUserOp signs the EIP-712 SafeOp hash. The signature is prefixed with
+// the validAfter and validUntil timestamps, which are left unbounded.
+func (s *SafeAccount) signUserOp(ctx *userop.IUserOperationMiddlewareCtx) error {
+	validAfter, validUntil := big.NewInt(0), big.NewInt(0)
+	hash, err := safeOpHash(ctx.Op, ctx.EntryPoint, ctx.ChainID, s.module, validAfter, validUntil)
+	if err != nil {
+		return err
+	}
+
+	signature, err := crypto.Sign(hash, s.signer)
+	if err != nil {
+		return err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+
+	ctx.Op.Signature = hexutil.Encode(bytes.Join([][]byte{
+		common.LeftPadBytes(validAfter.Bytes(), 6),
+		common.LeftPadBytes(validUntil.Bytes(), 6),
+		signature,
+	}, nil))
+	return nil
+}
+
+// safeInitCode returns the initCode that deploys a Safe proxy owned by owner,
+// with module enabled through the AddModulesLib and set as fallback handler.
+func safeInitCode(factory common.Address, owner common.Address, module common.Address, salt *big.Int) ([]byte, error) {
+	factoryAbi, err := abi.JSON(strings.NewReader(typechain.SafeProxyFactoryContract))
+	if err != nil {
+		return nil, err
+	}
+	safeAbi, err := abi.JSON(strings.NewReader(typechain.SafeContract))
+	if err != nil {
+		return nil, err
+	}
+	addModulesAbi, err := abi.JSON(strings.NewReader(typechain.AddModulesLibContract))
+	if err != nil {
+		return nil, err
+	}
+
+	enableModules, err := addModulesAbi.Pack("enableModules", []common.Address{module})
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := safeAbi.Pack("setup",
+		[]common.Address{owner},
+		big.NewInt(1),
+		common.HexToAddress(constants.SAFE_ADD_MODULES_LIB),
+		enableModules,
+		module,
+		common.Address{},
+		big.NewInt(0),
+		common.Address{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	createProxy, err := factoryAbi.Pack("createProxyWithNonce", common.HexToAddress(constants.SAFE_SINGLETON), initializer, salt)
+	if err != nil {
+		return nil, err
+	}
+	return append(factory.Bytes(), createProxy...), nil
+}
+
+// safeOpHash returns the EIP-712 hash of the SafeOp the Safe4337Module verifies.
+func safeOpHash(op *userop.IUserOperation, entryPoint common.Address, chainID *big.Int, module common.Address, validAfter, validUntil *big.Int) ([]byte, error) {
+	if chainID == nil {
+		return nil, fmt.Errorf("chain ID is required to compute the SafeOp hash")
+	}
+
+	initCode, err := hexutil.Decode(op.InitCode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid initCode: %w", err)
+	}
+	callData, err := hexutil.Decode(op.CallData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callData: %w", err)
+	}
+	paymasterAndData, err := hexutil.Decode(op.PaymasterAndData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid paymasterAndData: %w", err)
+	}
+
+	domain, err := utils.EncodeABI(
+		[]string{"bytes32", "uint256", "address"},
+		[]interface{}{safeDomainTypeHash, chainID, module},
+	)
+	if err != nil {
+		return nil, err
+	}
+	safeOp, err := utils.EncodeABI(
+		[]string{"bytes32", "address", "uint256", "bytes32", "bytes32", "uint256", "uint256", "uint256", "uint256", "uint256", "bytes32", "uint48", "uint48", "address"},
+		[]interface{}{
+			safeOpTypeHash,
+			op.Sender,
+			op.Nonce,
+			crypto.Keccak256Hash(initCode),
+			crypto.Keccak256Hash(callData),
+			op.CallGasLimit,
+			op.VerificationGasLimit,
+			op.PreVerificationGas,
+			op.MaxFeePerGas,
+			op.MaxPriorityFeePerGas,
+			crypto.Keccak256Hash(paymasterAndData),
+			validAfter,
+			validUntil,
+			entryPoint,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.Keccak256([]byte{0x19, 0x01}, crypto.Keccak256(domain), crypto.Keccak256(safeOp)), nil
+}
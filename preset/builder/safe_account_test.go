@@ -0,0 +1,149 @@
+package preset
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+func TestSafeAccount_BuildOp(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(signer.PublicKey)
+
+	chain := &testChain{
+		sender: common.HexToAddress("0x5afe5afe5afe5afe5afe5afe5afe5afe5afe5afe"),
+		nonce:  big.NewInt(0),
+		code:   "0x",
+	}
+	server := newTestChain(t, chain)
+
+	safe, err := NewSafeAccount(signer, server.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, chain.sender, safe.GetSender())
+
+	// The initCode deploys the singleton through the proxy factory with the
+	// module enabled and set as fallback handler.
+	assert.Equal(t, common.HexToAddress(constants.SAFE_PROXY_FACTORY).Bytes(), chain.initCode[:20])
+	factoryAbi, err := abi.JSON(strings.NewReader(typechain.SafeProxyFactoryContract))
+	require.NoError(t, err)
+	createProxy, err := factoryAbi.Methods["createProxyWithNonce"].Inputs.Unpack(chain.initCode[24:])
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress(constants.SAFE_SINGLETON), createProxy[0])
+	safeAbi, err := abi.JSON(strings.NewReader(typechain.SafeContract))
+	require.NoError(t, err)
+	setup, err := safeAbi.Methods["setup"].Inputs.Unpack(createProxy[1].([]byte)[4:])
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{owner}, setup[0])
+	assert.Equal(t, common.HexToAddress(constants.SAFE_ADD_MODULES_LIB), setup[2])
+	assert.Equal(t, common.HexToAddress(constants.SAFE_4337_MODULE), setup[4])
+
+	_, err = safe.ExecuteBatch([]userop.Call{{To: common.HexToAddress("0x02"), Data: []byte{0x01}}})
+	require.NoError(t, err)
+
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT)
+	op, err := safe.BuildOp(entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+
+	module, err := abi.JSON(strings.NewReader(typechain.Safe4337ModuleContract))
+	require.NoError(t, err)
+	args, err := module.Methods["executeUserOp"].Inputs.Unpack(hexutil.MustDecode(op.CallData)[4:])
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress(constants.NewSafe().GetMultiSend()["1"]), args[0])
+	assert.Equal(t, operationDelegateCall, args[3])
+
+	// The signature is validAfter || validUntil || ECDSA signature of the SafeOp.
+	signature := hexutil.MustDecode(op.Signature)
+	require.Len(t, signature, 12+65)
+	assert.Equal(t, make([]byte, 12), signature[:12])
+
+	hash := safeOpTypedDataHash(t, op, entryPoint)
+	sig := signature[12:]
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(hash, sig)
+	require.NoError(t, err)
+	assert.Equal(t, owner, crypto.PubkeyToAddress(*pub))
+}
+
+// safeOpTypedDataHash hashes op with the generic EIP-712 implementation.
+func safeOpTypedDataHash(t *testing.T, op *userop.IUserOperation, entryPoint common.Address) []byte {
+	t.Helper()
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"SafeOp": {
+				{Name: "safe", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "initCode", Type: "bytes"},
+				{Name: "callData", Type: "bytes"},
+				{Name: "callGasLimit", Type: "uint256"},
+				{Name: "verificationGasLimit", Type: "uint256"},
+				{Name: "preVerificationGas", Type: "uint256"},
+				{Name: "maxFeePerGas", Type: "uint256"},
+				{Name: "maxPriorityFeePerGas", Type: "uint256"},
+				{Name: "paymasterAndData", Type: "bytes"},
+				{Name: "validAfter", Type: "uint48"},
+				{Name: "validUntil", Type: "uint48"},
+				{Name: "entryPoint", Type: "address"},
+			},
+		},
+		PrimaryType: "SafeOp",
+		Domain: apitypes.TypedDataDomain{
+			ChainId:           math.NewHexOrDecimal256(1),
+			VerifyingContract: constants.SAFE_4337_MODULE,
+		},
+		Message: apitypes.TypedDataMessage{
+			"safe":                 op.Sender.Hex(),
+			"nonce":                op.Nonce.String(),
+			"initCode":             op.InitCode,
+			"callData":             op.CallData,
+			"callGasLimit":         op.CallGasLimit.String(),
+			"verificationGasLimit": op.VerificationGasLimit.String(),
+			"preVerificationGas":   op.PreVerificationGas.String(),
+			"maxFeePerGas":         op.MaxFeePerGas.String(),
+			"maxPriorityFeePerGas": op.MaxPriorityFeePerGas.String(),
+			"paymasterAndData":     op.PaymasterAndData,
+			"validAfter":           "0",
+			"validUntil":           "0",
+			"entryPoint":           entryPoint.Hex(),
+		},
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestSafeAccount_WithoutMultiSend(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chain := &testChain{
+		sender:  common.HexToAddress("0x3F2a1c5C4b8D2c5fE0bB8d7aD3D7F2E1c0A9b8c7"),
+		nonce:   big.NewInt(0),
+		code:    "0x",
+		chainID: "0xdeadbeef",
+	}
+	server := newTestChain(t, chain)
+
+	account, err := NewSafeAccount(signer, server.URL, nil)
+	require.NoError(t, err, "a chain without MultiSend only rules out batches")
+
+	_, err = account.Execute(userop.Call{To: common.HexToAddress("0x02"), Data: []byte{0xaa}})
+	assert.NoError(t, err)
+	_, err = account.ExecuteBatch([]userop.Call{{To: common.HexToAddress("0x02")}})
+	assert.ErrorContains(t, err, "multisend contract not deployed on network: 3735928559")
+}
@@ -0,0 +1,83 @@
+package preset
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/preset/middleware"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+// SimpleAccount is a UserOperationBuilder preset for the eth-infinitism SimpleAccount.
+type SimpleAccount struct {
+	*account
+	proxy abi.ABI
+}
+
+// NewSimpleAccount creates a SimpleAccount owned by signer. The sender is the
+// counterfactual address returned by the EntryPoint for the factory initCode.
+func NewSimpleAccount(signer *ecdsa.PrivateKey, rpcUrl string, opts *userop.IPresetBuilderOpts) (*SimpleAccount, error) {
+	opts = withPresetDefaults(opts, constants.SIMPLE_ACCOUNT_FACTORY)
+
+	factory, err := abi.JSON(strings.NewReader(typechain.SimpleAccountFactoryContract))
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := abi.JSON(strings.NewReader(typechain.SimpleAccountContract))
+	if err != nil {
+		return nil, err
+	}
+
+	owner := crypto.PubkeyToAddress(signer.PublicKey)
+	createAccount, err := factory.Pack("createAccount", owner, opts.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := newAccount(signer, rpcUrl, opts, append(opts.FactoryAddress.Bytes(), createAccount...))
+	if err != nil {
+		return nil, err
+	}
+	a := &SimpleAccount{account: base, proxy: proxy}
+	a.UseMiddleware(middleware.EOASignature(signer))
+
+	return a, nil
+}
+
+// Execute sets the callData to a single call from the account.
+func (a *SimpleAccount) Execute(call userop.Call) (*SimpleAccount, error) {
+	callData, err := a.proxy.Pack("execute", call.To, valueOrZero(call.Value), call.Data)
+	if err != nil {
+		return nil, err
+	}
+	a.SetCallData(hexutil.Encode(callData))
+	return a, nil
+}
+
+// ExecuteBatch sets the callData to a batch of calls from the account.
+// SimpleAccount v0.6 cannot send value in a batch.
+func (a *SimpleAccount) ExecuteBatch(calls []userop.Call) (*SimpleAccount, error) {
+	dest := make([]common.Address, len(calls))
+	data := make([][]byte, len(calls))
+	for i, call := range calls {
+		if valueOrZero(call.Value).Sign() != 0 {
+			return nil, fmt.Errorf("call %d: SimpleAccount executeBatch does not support value", i)
+		}
+		dest[i] = call.To
+		data[i] = call.Data
+	}
+
+	callData, err := a.proxy.Pack("executeBatch", dest, data)
+	if err != nil {
+		return nil, err
+	}
+	a.SetCallData(hexutil.Encode(callData))
+	return a, nil
+}
@@ -0,0 +1,102 @@
+package preset
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+	"github.com/withsilasogar/userop/typechain"
+)
+
+func TestSimpleAccount_BuildOp(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(signer.PublicKey)
+
+	chain := &testChain{
+		sender: common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72"),
+		nonce:  big.NewInt(0),
+		code:   "0x",
+	}
+	server := newTestChain(t, chain)
+
+	account, err := NewSimpleAccount(signer, server.URL, &userop.IPresetBuilderOpts{Salt: big.NewInt(3)})
+	require.NoError(t, err)
+	assert.Equal(t, chain.sender, account.GetSender())
+
+	factory, err := abi.JSON(strings.NewReader(typechain.SimpleAccountFactoryContract))
+	require.NoError(t, err)
+	createAccount, err := factory.Pack("createAccount", owner, big.NewInt(3))
+	require.NoError(t, err)
+	expectedInitCode := append(common.HexToAddress(constants.SIMPLE_ACCOUNT_FACTORY).Bytes(), createAccount...)
+	assert.Equal(t, expectedInitCode, chain.initCode)
+
+	to := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	_, err = account.Execute(userop.Call{To: to, Value: big.NewInt(1000), Data: []byte{0x01}})
+	require.NoError(t, err)
+
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT)
+	op, err := account.BuildOp(entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, hexutil.Encode(expectedInitCode), op.InitCode)
+	assert.Equal(t, int64(0), op.Nonce.Int64())
+	assert.Equal(t, int64(113000000), op.MaxPriorityFeePerGas.Int64())
+	assert.Equal(t, int64(2113000000), op.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(46000), op.PreVerificationGas.Int64())
+	assert.Equal(t, int64(100000), op.VerificationGasLimit.Int64())
+	assert.Equal(t, int64(33300), op.CallGasLimit.Int64())
+
+	proxy, err := abi.JSON(strings.NewReader(typechain.SimpleAccountContract))
+	require.NoError(t, err)
+	args, err := proxy.Methods["execute"].Inputs.Unpack(hexutil.MustDecode(op.CallData)[4:])
+	require.NoError(t, err)
+	assert.Equal(t, to, args[0])
+	assert.Equal(t, big.NewInt(1000), args[1])
+
+	hash, err := userop.GetUserOpHash(op, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, owner, recoverSigner(t, hash, hexutil.MustDecode(op.Signature)))
+
+	// Once deployed, the initCode is dropped and the nonce comes from the EntryPoint.
+	chain.code = "0x6080"
+	chain.nonce = big.NewInt(5)
+	account.ResetOp()
+	op, err = account.BuildOp(entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, "0x", op.InitCode)
+	assert.Equal(t, int64(5), op.Nonce.Int64())
+}
+
+func TestSimpleAccount_ExecuteBatch(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	server := newTestChain(t, &testChain{sender: common.HexToAddress("0x01"), nonce: big.NewInt(0), code: "0x"})
+
+	account, err := NewSimpleAccount(signer, server.URL, nil)
+	require.NoError(t, err)
+
+	calls := []userop.Call{
+		{To: common.HexToAddress("0x02"), Data: []byte{0x01}},
+		{To: common.HexToAddress("0x03"), Data: []byte{0x02}},
+	}
+	_, err = account.ExecuteBatch(calls)
+	require.NoError(t, err)
+
+	proxy, err := abi.JSON(strings.NewReader(typechain.SimpleAccountContract))
+	require.NoError(t, err)
+	args, err := proxy.Methods["executeBatch"].Inputs.Unpack(hexutil.MustDecode(account.GetCallData())[4:])
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{calls[0].To, calls[1].To}, args[0])
+	assert.Equal(t, [][]byte{{0x01}, {0x02}}, args[1])
+
+	_, err = account.ExecuteBatch([]userop.Call{{To: common.HexToAddress("0x02"), Value: big.NewInt(1)}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/models"
+)
+
+// DummySignature is a well formed 65 byte ECDSA signature used to estimate
+// ops that have not been given a preset specific dummy signature.
+const DummySignature = "0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c"
+
+// EstimateUserOperationGas sets preVerificationGas, verificationGasLimit and
+// callGasLimit from eth_estimateUserOperationGas on the bundler. For
+// EntryPoint v0.7 and later, a paymasterVerificationGasLimit in the estimate
+// is packed into paymasterAndData when the op has a paymaster.
+func EstimateUserOperationGas(provider *userop.BundlerJsonRpcProvider) userop.UserOperationMiddlewareFn {
+	return func(ctx *userop.IUserOperationMiddlewareCtx) error {
+		op := ctx.Op.Copy()
+		if op.Signature == "" || op.Signature == "0x" {
+			op.Signature = DummySignature
+		}
+
+		opJSON, err := userop.OpToJSON(op, ctx.Version())
+		if err != nil {
+			return err
+		}
+
+		var raw json.RawMessage
+		err = provider.CallContext(context.Background(), &raw, "eth_estimateUserOperationGas", opJSON, ctx.EntryPoint.Hex())
+		if err != nil {
+			return fmt.Errorf("failed to estimate user operation gas: %w", err)
+		}
+		estimate, err := (&models.GasEstimate{}).FromJSON(raw)
+		if err != nil {
+			return fmt.Errorf("failed to decode gas estimate: %w", err)
+		}
+
+		preVerificationGas, err := parseQuantity("preVerificationGas", estimate.PreVerificationGas)
+		if err != nil {
+			return err
+		}
+		verificationGasLimit, err := parseQuantity("verificationGasLimit", estimate.GetVerificationGasLimit())
+		if err != nil {
+			return err
+		}
+		callGasLimit, err := parseQuantity("callGasLimit", estimate.CallGasLimit)
+		if err != nil {
+			return err
+		}
+
+		paymasterAndData := ctx.Op.PaymasterAndData
+		if estimate.PaymasterVerificationGasLimit != nil && ctx.Version() != userop.EntryPointV06 {
+			paymasterAndData, err = estimatePaymasterAndData(ctx.Op, *estimate.PaymasterVerificationGasLimit)
+			if err != nil {
+				return err
+			}
+		}
+
+		ctx.Op.PreVerificationGas = preVerificationGas
+		ctx.Op.VerificationGasLimit = verificationGasLimit
+		ctx.Op.CallGasLimit = callGasLimit
+		ctx.Op.PaymasterAndData = paymasterAndData
+		return nil
+	}
+}
+
+// estimatePaymasterAndData returns the v0.7 paymasterAndData of op with its
+// paymasterVerificationGasLimit replaced by the estimated value. Ops without
+// a paymaster are returned unchanged.
+func estimatePaymasterAndData(op *userop.IUserOperation, value string) (string, error) {
+	v07, err := op.ToV07()
+	if err != nil {
+		return "", err
+	}
+	if v07.Paymaster == (common.Address{}) {
+		return op.PaymasterAndData, nil
+	}
+	verificationGasLimit, err := parseQuantity("paymasterVerificationGasLimit", value)
+	if err != nil {
+		return "", err
+	}
+	return userop.PackPaymasterAndData(v07.Paymaster, verificationGasLimit, v07.PaymasterPostOpGasLimit, v07.PaymasterData)
+}
+
+// parseQuantity parses a hex or decimal quantity returned by an RPC.
+func parseQuantity(field, value string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(value, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s %q", field, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+)
+
+func TestEstimateUserOperationGas(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{
+		"eth_estimateUserOperationGas": map[string]interface{}{
+			"preVerificationGas":   "0xb3b0",
+			"verificationGasLimit": "0x186a0",
+			"callGasLimit":         "0x8214",
+		},
+	})
+	ctx := &userop.IUserOperationMiddlewareCtx{
+		Op:         userop.NewDefaultUserOperation(),
+		EntryPoint: common.HexToAddress(constants.ENTRY_POINT),
+		ChainID:    big.NewInt(1),
+	}
+
+	require.NoError(t, EstimateUserOperationGas(provider)(ctx))
+	assert.Equal(t, int64(46000), ctx.Op.PreVerificationGas.Int64())
+	assert.Equal(t, int64(100000), ctx.Op.VerificationGasLimit.Int64())
+	assert.Equal(t, int64(33300), ctx.Op.CallGasLimit.Int64())
+	assert.Equal(t, "0x", ctx.Op.Signature, "the dummy signature should not leak into the op")
+}
+
+func TestEstimateUserOperationGas_VerificationGas(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{
+		"eth_estimateUserOperationGas": map[string]interface{}{
+			"preVerificationGas": "46000",
+			"verificationGas":    "100000",
+			"callGasLimit":       "33300",
+		},
+	})
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation(), ChainID: big.NewInt(1)}
+
+	require.NoError(t, EstimateUserOperationGas(provider)(ctx))
+	assert.Equal(t, int64(100000), ctx.Op.VerificationGasLimit.Int64())
+}
+
+func TestEstimateUserOperationGas_PaymasterVerificationGasLimit(t *testing.T) {
+	paymaster := common.HexToAddress("0x0000000000000000000000000000000000000abc")
+	paymasterAndData, err := userop.PackPaymasterAndData(paymaster, big.NewInt(1), big.NewInt(20000), "0xabcd")
+	require.NoError(t, err)
+	provider := newTestProvider(t, map[string]interface{}{
+		"eth_estimateUserOperationGas": map[string]interface{}{
+			"preVerificationGas":            "0xb3b0",
+			"verificationGasLimit":          "0x186a0",
+			"callGasLimit":                  "0x8214",
+			"paymasterVerificationGasLimit": "0x7530",
+		},
+	})
+
+	for _, entryPoint := range []string{constants.ENTRY_POINT_V07, constants.ENTRY_POINT_V08} {
+		ctx := &userop.IUserOperationMiddlewareCtx{
+			Op:         userop.NewDefaultUserOperation(),
+			EntryPoint: common.HexToAddress(entryPoint),
+			ChainID:    big.NewInt(1),
+		}
+		ctx.Op.PaymasterAndData = paymasterAndData
+
+		require.NoError(t, EstimateUserOperationGas(provider)(ctx))
+		expected, err := userop.PackPaymasterAndData(paymaster, big.NewInt(30000), big.NewInt(20000), "0xabcd")
+		require.NoError(t, err)
+		assert.Equal(t, expected, ctx.Op.PaymasterAndData, entryPoint)
+		assert.Equal(t, int64(100000), ctx.Op.VerificationGasLimit.Int64())
+	}
+
+	ctx := &userop.IUserOperationMiddlewareCtx{
+		Op:         userop.NewDefaultUserOperation(),
+		EntryPoint: common.HexToAddress(constants.ENTRY_POINT_V07),
+		ChainID:    big.NewInt(1),
+	}
+	require.NoError(t, EstimateUserOperationGas(provider)(ctx))
+	assert.Equal(t, "0x", ctx.Op.PaymasterAndData, "ops without a paymaster are left alone")
+
+	ctx = &userop.IUserOperationMiddlewareCtx{
+		Op:         userop.NewDefaultUserOperation(),
+		EntryPoint: common.HexToAddress(constants.ENTRY_POINT),
+		ChainID:    big.NewInt(1),
+	}
+	ctx.Op.PaymasterAndData = "0x0000000000000000000000000000000000000abc1234"
+	require.NoError(t, EstimateUserOperationGas(provider)(ctx))
+	assert.Equal(t, "0x0000000000000000000000000000000000000abc1234", ctx.Op.PaymasterAndData, "v0.6 paymasterAndData has no gas limits")
+}
+
+func TestEstimateUserOperationGas_Error(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{})
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation(), ChainID: big.NewInt(1)}
+
+	assert.Error(t, EstimateUserOperationGas(provider)(ctx))
+}
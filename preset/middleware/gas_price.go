@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/withsilasogar/userop"
+)
+
+// errNoBaseFee is returned by EIP1559GasPrice on chains without a base fee.
+var errNoBaseFee = errors.New("latest block has no base fee")
+
+// EIP1559GasPrice sets maxFeePerGas and maxPriorityFeePerGas from the latest
+// base fee and eth_maxPriorityFeePerGas. The priority fee is bumped by 13% and
+// maxFeePerGas allows for the base fee to double.
+func EIP1559GasPrice(provider *userop.BundlerJsonRpcProvider) userop.UserOperationMiddlewareFn {
+	return func(ctx *userop.IUserOperationMiddlewareCtx) error {
+		var block struct {
+			BaseFeePerGas *hexutil.Big `json:"baseFeePerGas"`
+		}
+		if err := provider.CallContext(context.Background(), &block, "eth_getBlockByNumber", "latest", false); err != nil {
+			return fmt.Errorf("failed to fetch latest block: %w", err)
+		}
+		if block.BaseFeePerGas == nil {
+			return errNoBaseFee
+		}
+
+		var tip hexutil.Big
+		if err := provider.CallContext(context.Background(), &tip, "eth_maxPriorityFeePerGas"); err != nil {
+			return fmt.Errorf("failed to fetch max priority fee: %w", err)
+		}
+
+		buffer := new(big.Int).Div(tip.ToInt(), big.NewInt(100))
+		buffer.Mul(buffer, big.NewInt(13))
+		maxPriorityFeePerGas := new(big.Int).Add(tip.ToInt(), buffer)
+		maxFeePerGas := new(big.Int).Mul(block.BaseFeePerGas.ToInt(), big.NewInt(2))
+		maxFeePerGas.Add(maxFeePerGas, maxPriorityFeePerGas)
+
+		ctx.Op.MaxFeePerGas = maxFeePerGas
+		ctx.Op.MaxPriorityFeePerGas = maxPriorityFeePerGas
+		return nil
+	}
+}
+
+// LegacyGasPrice sets both fee fields to eth_gasPrice.
+func LegacyGasPrice(provider *userop.BundlerJsonRpcProvider) userop.UserOperationMiddlewareFn {
+	return func(ctx *userop.IUserOperationMiddlewareCtx) error {
+		var gasPrice hexutil.Big
+		if err := provider.CallContext(context.Background(), &gasPrice, "eth_gasPrice"); err != nil {
+			return fmt.Errorf("failed to fetch gas price: %w", err)
+		}
+
+		ctx.Op.MaxFeePerGas = new(big.Int).Set(gasPrice.ToInt())
+		ctx.Op.MaxPriorityFeePerGas = new(big.Int).Set(gasPrice.ToInt())
+		return nil
+	}
+}
+
+// GetGasPrice uses EIP1559GasPrice and falls back to LegacyGasPrice on chains
+// without a base fee or eth_maxPriorityFeePerGas. Any other error, such as a
+// timeout, is returned.
+func GetGasPrice(provider *userop.BundlerJsonRpcProvider) userop.UserOperationMiddlewareFn {
+	eip1559 := EIP1559GasPrice(provider)
+	legacy := LegacyGasPrice(provider)
+	return func(ctx *userop.IUserOperationMiddlewareCtx) error {
+		err := eip1559(ctx)
+		if errors.Is(err, errNoBaseFee) || isMethodNotFound(err) {
+			return legacy(ctx)
+		}
+		return err
+	}
+}
+
+// isMethodNotFound reports whether err is a JSON-RPC "method not found" response.
+func isMethodNotFound(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601
+}
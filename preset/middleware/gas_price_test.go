@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+)
+
+// newTestProvider serves each method in results with a fixed JSON-RPC result.
+// Methods that are missing return a "method not found" error.
+func newTestProvider(t *testing.T, results map[string]interface{}) *userop.BundlerJsonRpcProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if result, ok := results[req.Method]; ok {
+			resp["result"] = result
+		} else {
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := userop.NewBundlerJsonRpcProvider(server.URL)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestGetGasPrice_EIP1559(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{
+		"eth_getBlockByNumber":     map[string]interface{}{"baseFeePerGas": "0x3b9aca00"},
+		"eth_maxPriorityFeePerGas": "0x3b9aca00",
+	})
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation(), ChainID: big.NewInt(1)}
+
+	require.NoError(t, GetGasPrice(provider)(ctx))
+	assert.Equal(t, int64(1130000000), ctx.Op.MaxPriorityFeePerGas.Int64())
+	assert.Equal(t, int64(3130000000), ctx.Op.MaxFeePerGas.Int64())
+}
+
+func TestGetGasPrice_LegacyFallback(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{
+		"eth_getBlockByNumber": map[string]interface{}{"number": "0x1"},
+		"eth_gasPrice":         "0x2540be400",
+	})
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation(), ChainID: big.NewInt(1)}
+
+	assert.Error(t, EIP1559GasPrice(provider)(ctx))
+	require.NoError(t, GetGasPrice(provider)(ctx))
+	assert.Equal(t, int64(10000000000), ctx.Op.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(10000000000), ctx.Op.MaxPriorityFeePerGas.Int64())
+}
+
+func TestGetGasPrice_NoPriorityFee(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{
+		"eth_getBlockByNumber": map[string]interface{}{"baseFeePerGas": "0x3b9aca00"},
+		"eth_gasPrice":         "0x2540be400",
+	})
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation(), ChainID: big.NewInt(1)}
+
+	require.NoError(t, GetGasPrice(provider)(ctx))
+	assert.Equal(t, int64(10000000000), ctx.Op.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(10000000000), ctx.Op.MaxPriorityFeePerGas.Int64())
+}
+
+func TestGetGasPrice_TransportError(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		methods = append(methods, req.Method)
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+	provider, err := userop.NewBundlerJsonRpcProvider(server.URL)
+	require.NoError(t, err)
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation(), ChainID: big.NewInt(1)}
+
+	err = GetGasPrice(provider)(ctx)
+	assert.ErrorContains(t, err, "failed to fetch latest block")
+	assert.Equal(t, []string{"eth_getBlockByNumber"}, methods, "a transport error does not fall back to eth_gasPrice")
+	assert.Equal(t, int64(0), ctx.Op.MaxFeePerGas.Int64())
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/models"
+)
+
+// VerifyingPaymaster requests sponsorship from paymasterRpc with
+// pm_sponsorUserOperation and applies the returned paymasterAndData and gas
+// fields. pmContext is passed through to the paymaster as is and may be nil.
+func VerifyingPaymaster(paymasterRpc string, pmContext interface{}) userop.UserOperationMiddlewareFn {
+	return VerifyingPaymasterWithOpts(paymasterRpc, pmContext, nil)
+}
+
+// VerifyingPaymasterWithOpts is VerifyingPaymaster with connection options,
+// such as API key headers, for paymasterRpc.
+func VerifyingPaymasterWithOpts(paymasterRpc string, pmContext interface{}, opts *userop.IEndpointOpts) userop.UserOperationMiddlewareFn {
+	provider, err := userop.NewBundlerJsonRpcProviderWithOpts(paymasterRpc, &userop.IProviderOpts{
+		EndpointOpts: map[string]*userop.IEndpointOpts{userop.EndpointNode: opts},
+	})
+	return func(ctx *userop.IUserOperationMiddlewareCtx) error {
+		if err != nil {
+			return fmt.Errorf("failed to connect to paymaster: %w", err)
+		}
+		return sponsorUserOperation(provider, ctx, pmContext)
+	}
+}
+
+// sponsorUserOperation calls pm_sponsorUserOperation and applies the result to ctx.Op.
+func sponsorUserOperation(provider *userop.BundlerJsonRpcProvider, ctx *userop.IUserOperationMiddlewareCtx, pmContext interface{}) error {
+	opJSON, err := ctx.OpToJSON()
+	if err != nil {
+		return err
+	}
+
+	var raw json.RawMessage
+	err = provider.CallContext(context.Background(), &raw, "pm_sponsorUserOperation", opJSON, ctx.EntryPoint.Hex(), pmContext)
+	if err != nil {
+		return fmt.Errorf("failed to sponsor user operation: %w", err)
+	}
+	result, err := (&models.VerifyingPaymasterResult{}).FromJSON(raw)
+	if err != nil {
+		return fmt.Errorf("failed to decode paymaster result: %w", err)
+	}
+	paymasterAndData, err := resultPaymasterAndData(result)
+	if err != nil {
+		return err
+	}
+
+	gas := map[string]string{
+		"preVerificationGas":   result.PreVerificationGas,
+		"verificationGasLimit": result.VerificationGasLimit,
+		"callGasLimit":         result.CallGasLimit,
+	}
+	limits := make(map[string]*big.Int, len(gas))
+	for field, value := range gas {
+		// Paymasters that do not re-estimate leave the gas fields out.
+		if value == "" {
+			continue
+		}
+		n, err := parseQuantity(field, value)
+		if err != nil {
+			return err
+		}
+		limits[field] = n
+	}
+
+	ctx.Op.PaymasterAndData = paymasterAndData
+	if n, ok := limits["preVerificationGas"]; ok {
+		ctx.Op.PreVerificationGas = n
+	}
+	if n, ok := limits["verificationGasLimit"]; ok {
+		ctx.Op.VerificationGasLimit = n
+	}
+	if n, ok := limits["callGasLimit"]; ok {
+		ctx.Op.CallGasLimit = n
+	}
+	return nil
+}
+
+// resultPaymasterAndData returns the paymasterAndData of result, packing the
+// split fields returned by EntryPoint v0.7 paymasters.
+func resultPaymasterAndData(result *models.VerifyingPaymasterResult) (string, error) {
+	if result.PaymasterAndData != "" {
+		return result.PaymasterAndData, nil
+	}
+	if result.Paymaster == "" {
+		return "", fmt.Errorf("paymaster returned no paymasterAndData")
+	}
+	if !common.IsHexAddress(result.Paymaster) {
+		return "", fmt.Errorf("invalid paymaster %q", result.Paymaster)
+	}
+
+	verificationGasLimit, err := parseQuantity("paymasterVerificationGasLimit", result.PaymasterVerificationGasLimit)
+	if err != nil {
+		return "", err
+	}
+	postOpGasLimit, err := parseQuantity("paymasterPostOpGasLimit", result.PaymasterPostOpGasLimit)
+	if err != nil {
+		return "", err
+	}
+	paymasterData := result.PaymasterData
+	if paymasterData == "" {
+		paymasterData = "0x"
+	}
+	return userop.PackPaymasterAndData(common.HexToAddress(result.Paymaster), verificationGasLimit, postOpGasLimit, paymasterData)
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+)
+
+func TestVerifyingPaymaster(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+		assert.Equal(t, "pm_sponsorUserOperation", req.Method)
+		if !assert.Len(t, req.Params, 3) {
+			return
+		}
+
+		var entryPoint common.Address
+		if !assert.NoError(t, json.Unmarshal(req.Params[1], &entryPoint)) {
+			return
+		}
+		assert.Equal(t, common.HexToAddress(constants.ENTRY_POINT), entryPoint)
+		assert.JSONEq(t, `{"type":"payg"}`, string(req.Params[2]))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result": map[string]string{
+				"paymasterAndData":     "0xe93eca6595fe94091dc1af46aac2a8b5d7990770abcd",
+				"preVerificationGas":   "0xb3b0",
+				"verificationGasLimit": "0x186a0",
+				"callGasLimit":         "0x8214",
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := &userop.IUserOperationMiddlewareCtx{
+		Op:         userop.NewDefaultUserOperation(),
+		EntryPoint: common.HexToAddress(constants.ENTRY_POINT),
+		ChainID:    big.NewInt(1),
+	}
+	require.NoError(t, VerifyingPaymaster(server.URL, map[string]string{"type": "payg"})(ctx))
+	assert.Equal(t, "0xe93eca6595fe94091dc1af46aac2a8b5d7990770abcd", ctx.Op.PaymasterAndData)
+	assert.Equal(t, int64(46000), ctx.Op.PreVerificationGas.Int64())
+	assert.Equal(t, int64(100000), ctx.Op.VerificationGasLimit.Int64())
+	assert.Equal(t, int64(33300), ctx.Op.CallGasLimit.Int64())
+}
+
+func TestVerifyingPaymaster_Error(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{})
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation(), ChainID: big.NewInt(1)}
+
+	assert.Error(t, sponsorUserOperation(provider, ctx, nil))
+	assert.Equal(t, "0x", ctx.Op.PaymasterAndData)
+}
+
+func TestVerifyingPaymaster_V07(t *testing.T) {
+	provider := newTestProvider(t, map[string]interface{}{
+		"pm_sponsorUserOperation": map[string]string{
+			"paymaster":                     "0xe93eca6595fe94091dc1af46aac2a8b5d7990770",
+			"paymasterVerificationGasLimit": "0xea60",
+			"paymasterPostOpGasLimit":       "0x2710",
+			"paymasterData":                 "0xabcd",
+			"preVerificationGas":            "0xb3b0",
+			"verificationGasLimit":          "0x186a0",
+			"callGasLimit":                  "0x8214",
+		},
+	})
+	ctx := &userop.IUserOperationMiddlewareCtx{
+		Op:         userop.NewDefaultUserOperation(),
+		EntryPoint: common.HexToAddress(constants.ENTRY_POINT_V07),
+		ChainID:    big.NewInt(1),
+	}
+
+	require.NoError(t, sponsorUserOperation(provider, ctx, nil))
+	expected, err := userop.PackPaymasterAndData(common.HexToAddress("0xe93eca6595fe94091dc1af46aac2a8b5d7990770"), big.NewInt(60000), big.NewInt(10000), "0xabcd")
+	require.NoError(t, err)
+	assert.Equal(t, expected, ctx.Op.PaymasterAndData)
+	assert.Equal(t, int64(46000), ctx.Op.PreVerificationGas.Int64())
+}
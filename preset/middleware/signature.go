@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop"
+)
+
+// EOASignature signs the userOpHash with signer. EntryPoint v0.8 hashes are
+// EIP-712 digests and are signed as is; older hashes are signed as an
+// EIP-191 personal message. It should be the last middleware in the chain so
+// the signature covers the final gas and paymaster fields.
+func EOASignature(signer *ecdsa.PrivateKey) userop.UserOperationMiddlewareFn {
+	return func(ctx *userop.IUserOperationMiddlewareCtx) error {
+		hash, err := ctx.GetUserOpHash()
+		if err != nil {
+			return err
+		}
+		var signature string
+		if ctx.Version() == userop.EntryPointV08 {
+			signature, err = SignHash(signer, hash)
+		} else {
+			signature, err = SignMessage(signer, hash)
+		}
+		if err != nil {
+			return err
+		}
+		ctx.Op.Signature = signature
+		return nil
+	}
+}
+
+// SignMessage signs hash as an EIP-191 personal message and returns the 65
+// byte signature with v set to 27 or 28.
+func SignMessage(signer *ecdsa.PrivateKey, hash []byte) (string, error) {
+	return SignHash(signer, accounts.TextHash(hash))
+}
+
+// SignHash signs the 32 byte digest hash without a prefix and returns the 65
+// byte signature with v set to 27 or 28.
+func SignHash(signer *ecdsa.PrivateKey, hash []byte) (string, error) {
+	signature, err := crypto.Sign(hash, signer)
+	if err != nil {
+		return "", err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(signature), nil
+}
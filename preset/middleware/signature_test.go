@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop"
+	"github.com/withsilasogar/userop/constants"
+)
+
+func TestEOASignature(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	ctx := &userop.IUserOperationMiddlewareCtx{
+		Op:         userop.NewDefaultUserOperation(),
+		EntryPoint: common.HexToAddress(constants.ENTRY_POINT),
+		ChainID:    big.NewInt(1),
+	}
+
+	require.NoError(t, EOASignature(signer)(ctx))
+	signature := hexutil.MustDecode(ctx.Op.Signature)
+	require.Len(t, signature, 65)
+	assert.Contains(t, []byte{27, 28}, signature[crypto.RecoveryIDOffset])
+
+	hash, err := ctx.GetUserOpHash()
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash(hash), signature)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(signer.PublicKey), crypto.PubkeyToAddress(*pub))
+}
+
+func TestEOASignature_V08(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	ctx := &userop.IUserOperationMiddlewareCtx{
+		Op:         userop.NewDefaultUserOperation(),
+		EntryPoint: common.HexToAddress(constants.ENTRY_POINT_V08),
+		ChainID:    big.NewInt(1),
+	}
+
+	require.NoError(t, EOASignature(signer)(ctx))
+	signature := hexutil.MustDecode(ctx.Op.Signature)
+	require.Len(t, signature, 65)
+
+	hash, err := userop.GetUserOpHashV08(ctx.Op, ctx.EntryPoint, ctx.ChainID)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(hash, signature)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(signer.PublicKey), crypto.PubkeyToAddress(*pub), "v0.8 accounts recover the raw EIP-712 digest")
+}
+
+func TestEOASignature_MissingChainID(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	ctx := &userop.IUserOperationMiddlewareCtx{Op: userop.NewDefaultUserOperation()}
+
+	assert.Error(t, EOASignature(signer)(ctx))
+	assert.Equal(t, "0x", ctx.Op.Signature)
+}
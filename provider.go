@@ -2,53 +2,359 @@ package userop
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// Names of the upstream endpoints used by the default routes. The node
+// endpoint is the RPC the provider was created with.
+const (
+	EndpointNode      = "node"
+	EndpointBundler   = "bundler"
+	EndpointPaymaster = "paymaster"
+)
+
+// defaultRoutes sends user operation and bundler vendor methods to the
+// bundler and paymaster methods to the paymaster. A trailing * matches any
+// method with that prefix.
+var defaultRoutes = map[string]string{
+	"eth_sendUserOperation":        EndpointBundler,
+	"eth_estimateUserOperationGas": EndpointBundler,
+	"eth_getUserOperationByHash":   EndpointBundler,
+	"eth_getUserOperationReceipt":  EndpointBundler,
+	"eth_supportedEntryPoints":     EndpointBundler,
+	"debug_bundler_*":              EndpointBundler,
+	"pimlico_*":                    EndpointBundler,
+	"rundler_*":                    EndpointBundler,
+	"pm_*":                         EndpointPaymaster,
+}
+
 // BundlerJsonRpcProvider is a wrapper over JsonRPC, specifically for the Bundler RPC.
+// Each method is routed to a named upstream endpoint. Methods without a
+// route, or routed to an endpoint that is not set, go to the node.
+// SetHeader and SupportsSubscriptions of the embedded client only apply to
+// the node.
 type BundlerJsonRpcProvider struct {
 	*rpc.Client
-	bundlerRpc     *rpc.Client
-	bundlerMethods map[string]struct{}
+	mu        sync.RWMutex
+	endpoints map[string]*endpoint
+	routes    map[string]string
+	broadcast bool
 }
 
-// NewBundlerJsonRpcProvider creates a new BundlerJsonRpcProvider with the given URL and HTTP client.
+// NewBundlerJsonRpcProvider creates a new BundlerJsonRpcProvider for the node at
+// url, which may be an HTTP(S) or WebSocket URL or an IPC path.
 func NewBundlerJsonRpcProvider(url string) (*BundlerJsonRpcProvider, error) {
-	rpcClient, err := rpc.DialHTTP(url)
+	return NewBundlerJsonRpcProviderWithOpts(url, nil)
+}
+
+// NewBundlerJsonRpcProviderWithOpts creates a new BundlerJsonRpcProvider for
+// the node at url, with the extra endpoints and routes of opts.
+func NewBundlerJsonRpcProviderWithOpts(url string, opts *IProviderOpts) (*BundlerJsonRpcProvider, error) {
+	var nodeOpts *IEndpointOpts
+	if opts != nil {
+		nodeOpts = opts.EndpointOpts[EndpointNode]
+	}
+	rpcClient, err := dialEndpoint(context.Background(), url, nodeOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RPC client: %w", err)
 	}
 
+	provider := NewBundlerJsonRpcProviderWithClient(rpcClient)
+	node := provider.endpoints[EndpointNode]
+	node.upstreams[0].url = url
+	if nodeOpts != nil {
+		node.timeout = nodeOpts.Timeout
+	}
+	if err := provider.applyOpts(opts); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// NewBundlerJsonRpcProviderWithClient creates a new BundlerJsonRpcProvider on top of an existing RPC client.
+func NewBundlerJsonRpcProviderWithClient(rpcClient *rpc.Client) *BundlerJsonRpcProvider {
+	routes := make(map[string]string, len(defaultRoutes))
+	for pattern, endpoint := range defaultRoutes {
+		routes[pattern] = endpoint
+	}
+	node := &endpoint{upstreams: []*upstream{{client: rpcClient}}}
 	return &BundlerJsonRpcProvider{
-		Client: rpcClient,
-		bundlerMethods: map[string]struct{}{
-			"eth_sendUserOperation":        {},
-			"eth_estimateUserOperationGas": {},
-			"eth_getUserOperationByHash":   {},
-			"eth_getUserOperationReceipt":  {},
-			"eth_supportedEntryPoints":     {},
-		},
-	}, nil
+		Client:    rpcClient,
+		endpoints: map[string]*endpoint{EndpointNode: node},
+		routes:    routes,
+	}
+}
+
+// applyOpts dials the endpoints of opts and adds its routes.
+func (p *BundlerJsonRpcProvider) applyOpts(opts *IProviderOpts) error {
+	if opts == nil {
+		return nil
+	}
+	for name, url := range opts.Endpoints {
+		if err := p.SetEndpointWithOpts(name, url, opts.EndpointOpts[name]); err != nil {
+			return err
+		}
+	}
+	for name, urls := range opts.FailoverEndpoints {
+		if err := p.SetEndpoints(name, urls, opts.EndpointOpts[name]); err != nil {
+			return err
+		}
+	}
+	for pattern, endpoint := range opts.Routes {
+		p.SetRoute(pattern, endpoint)
+	}
+	p.SetBroadcastUserOperations(opts.BroadcastUserOperations)
+	return nil
 }
 
 // SetBundlerRpc sets a new RPC client for the bundler.
 func (p *BundlerJsonRpcProvider) SetBundlerRpc(bundlerRpcURL string) error {
-	if bundlerRpcURL != "" {
-		bundlerRpcClient, err := rpc.DialHTTP(bundlerRpcURL)
-		if err != nil {
-			return fmt.Errorf("failed to create bundler RPC client: %w", err)
-		}
-		p.bundlerRpc = bundlerRpcClient
+	if bundlerRpcURL == "" {
+		return nil
+	}
+	return p.SetEndpoint(EndpointBundler, bundlerRpcURL)
+}
+
+// SetEndpoint connects to url and registers it under name, replacing any
+// previous endpoint with that name. The node endpoint cannot be replaced.
+func (p *BundlerJsonRpcProvider) SetEndpoint(name string, url string) error {
+	return p.SetEndpointWithOpts(name, url, nil)
+}
+
+// SetEndpointWithOpts is SetEndpoint with connection options for the endpoint.
+func (p *BundlerJsonRpcProvider) SetEndpointWithOpts(name string, url string, opts *IEndpointOpts) error {
+	return p.SetEndpoints(name, []string{url}, opts)
+}
+
+// SetEndpoints registers several URLs under name. Calls go to the first
+// healthy URL and fail over to the next one when a URL cannot be reached or
+// times out; JSON-RPC error responses are returned as is. A timed-out
+// eth_sendUserOperation is not resent, since the URL may have received it.
+// A URL that failed is only tried after the healthy ones for a while.
+// Without opts.Timeout each attempt is bounded by a default timeout of 30
+// seconds. The URLs previously set under name are closed.
+func (p *BundlerJsonRpcProvider) SetEndpoints(name string, urls []string, opts *IEndpointOpts) error {
+	if name == EndpointNode {
+		return fmt.Errorf("the %s endpoint is set when the provider is created", EndpointNode)
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("the %s endpoint needs at least one URL", name)
+	}
+	e, err := newEndpoint(urls, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create %s RPC client: %w", name, err)
+	}
+
+	p.mu.Lock()
+	previous := p.endpoints[name]
+	p.endpoints[name] = e
+	p.mu.Unlock()
+	if previous != nil {
+		previous.close()
 	}
 	return nil
 }
 
-// Call overrides the call method to handle bundler-specific methods.
-func (p *BundlerJsonRpcProvider) Call(ctx context.Context, method string, args interface{}, result interface{}) error {
-	if _, exists := p.bundlerMethods[method]; exists && p.bundlerRpc != nil {
-		return p.bundlerRpc.CallContext(ctx, result, method, args)
+// SetBroadcastUserOperations sets whether eth_sendUserOperation is sent to
+// every URL of its endpoint at once. The first accepted userOpHash is
+// returned and the call only fails if every URL rejects the op.
+func (p *BundlerJsonRpcProvider) SetBroadcastUserOperations(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.broadcast = enabled
+}
+
+// EndpointHealth returns the health of every URL of the named endpoint, or
+// nil if the endpoint is not set.
+func (p *BundlerJsonRpcProvider) EndpointHealth(name string) []IEndpointHealth {
+	p.mu.RLock()
+	e, ok := p.endpoints[name]
+	p.mu.RUnlock()
+	if !ok {
+		return nil
 	}
-	return p.Client.CallContext(ctx, result, method, args)
+	return e.health()
+}
+
+// SetRoute sends method to the named endpoint. A pattern ending in * routes
+// every method with that prefix; exact routes win over prefixes, and longer
+// prefixes over shorter ones.
+func (p *BundlerJsonRpcProvider) SetRoute(pattern string, endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes[pattern] = endpoint
+}
+
+// RemoveRoute deletes the route for pattern, so matching methods fall back
+// to a shorter prefix route or to the node.
+func (p *BundlerJsonRpcProvider) RemoveRoute(pattern string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.routes, pattern)
+}
+
+// Route returns the name of the endpoint that serves method.
+func (p *BundlerJsonRpcProvider) Route(method string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.route(method)
+}
+
+// route resolves method against the routing table. p.mu must be held.
+func (p *BundlerJsonRpcProvider) route(method string) string {
+	if endpoint, ok := p.routes[method]; ok {
+		return endpoint
+	}
+
+	endpoint, longest := EndpointNode, -1
+	for pattern, target := range p.routes {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && len(prefix) > longest && strings.HasPrefix(method, prefix) {
+			endpoint, longest = target, len(prefix)
+		}
+	}
+	return endpoint
+}
+
+// Call sends method with the given positional params and decodes the
+// response into result. The method is sent to the endpoint it is routed to.
+func (p *BundlerJsonRpcProvider) Call(ctx context.Context, method string, result interface{}, args ...interface{}) error {
+	return p.CallContext(ctx, result, method, args...)
+}
+
+// CallContext overrides the embedded client so that every method is sent to
+// the endpoint it is routed to.
+func (p *BundlerJsonRpcProvider) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	e := p.endpointFor(method)
+	if method == "eth_sendUserOperation" {
+		if len(e.upstreams) > 1 && p.broadcasts() {
+			return e.broadcast(ctx, result, method, args...)
+		}
+		return e.send(ctx, func(ctx context.Context, client *rpc.Client) error {
+			return client.CallContext(ctx, result, method, args...)
+		})
+	}
+	return e.call(ctx, func(ctx context.Context, client *rpc.Client) error {
+		return client.CallContext(ctx, result, method, args...)
+	})
+}
+
+// Subscribe overrides the embedded client so that the subscription is opened
+// on the first healthy URL of the endpoint namespace_subscribe is routed to.
+// It fails with rpc.ErrNotificationsUnsupported when that URL is served over
+// HTTP. A subscription ends with an error on Err() when its socket drops, and
+// is not restored when the client redials: callers re-open it.
+func (p *BundlerJsonRpcProvider) Subscribe(ctx context.Context, namespace string, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	return p.endpointFor(namespace + "_subscribe").ordered()[0].client.Subscribe(ctx, namespace, channel, args...)
+}
+
+// EthSubscribe registers a subscription under the "eth" namespace, e.g.
+// "newHeads" or "logs".
+func (p *BundlerJsonRpcProvider) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	return p.Subscribe(ctx, "eth", channel, args...)
+}
+
+// BatchCall sends elems as batch requests. The elements are split into one
+// batch per routed endpoint, sent in parallel, and their results and errors
+// written back to elems in the original order.
+func (p *BundlerJsonRpcProvider) BatchCall(ctx context.Context, elems []rpc.BatchElem) error {
+	type batch struct {
+		endpoint *endpoint
+		indexes  []int
+		elems    []rpc.BatchElem
+	}
+	var batches []*batch
+	for i, elem := range elems {
+		e := p.endpointFor(elem.Method)
+		var target *batch
+		for _, b := range batches {
+			if b.endpoint == e {
+				target = b
+				break
+			}
+		}
+		if target == nil {
+			target = &batch{endpoint: e}
+			batches = append(batches, target)
+		}
+		target.indexes = append(target.indexes, i)
+		target.elems = append(target.elems, elem)
+	}
+
+	errs := make([]error, len(batches))
+	var wg sync.WaitGroup
+	for i, b := range batches {
+		wg.Add(1)
+		go func(i int, b *batch) {
+			defer wg.Done()
+			errs[i] = b.endpoint.call(ctx, func(ctx context.Context, client *rpc.Client) error {
+				return client.BatchCallContext(ctx, b.elems)
+			})
+		}(i, b)
+	}
+	wg.Wait()
+
+	for _, b := range batches {
+		for j, i := range b.indexes {
+			elems[i].Error = b.elems[j].Error
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BatchCallContext overrides the embedded client so that batches are routed
+// like BatchCall.
+func (p *BundlerJsonRpcProvider) BatchCallContext(ctx context.Context, elems []rpc.BatchElem) error {
+	return p.BatchCall(ctx, elems)
+}
+
+// Notify overrides the embedded client so that the notification is sent to
+// the endpoint method is routed to.
+func (p *BundlerJsonRpcProvider) Notify(ctx context.Context, method string, args ...interface{}) error {
+	return p.endpointFor(method).call(ctx, func(ctx context.Context, client *rpc.Client) error {
+		return client.Notify(ctx, method, args...)
+	})
+}
+
+// SupportedModules overrides the embedded client so that rpc_modules is sent
+// to the endpoint it is routed to.
+func (p *BundlerJsonRpcProvider) SupportedModules() (map[string]string, error) {
+	var result map[string]string
+	err := p.CallContext(context.Background(), &result, "rpc_modules")
+	return result, err
+}
+
+// Close closes the clients of every endpoint, including the node.
+func (p *BundlerJsonRpcProvider) Close() {
+	p.mu.RLock()
+	endpoints := make([]*endpoint, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		endpoints = append(endpoints, e)
+	}
+	p.mu.RUnlock()
+	for _, e := range endpoints {
+		e.close()
+	}
+}
+
+// endpointFor returns the endpoint that should serve method.
+func (p *BundlerJsonRpcProvider) endpointFor(method string) *endpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if e, ok := p.endpoints[p.route(method)]; ok {
+		return e
+	}
+	return p.endpoints[EndpointNode]
+}
+
+// broadcasts reports whether eth_sendUserOperation is broadcast.
+func (p *BundlerJsonRpcProvider) broadcasts() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.broadcast
 }
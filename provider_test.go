@@ -0,0 +1,608 @@
+package userop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop/constants"
+)
+
+func TestBundlerJsonRpcProvider_Call(t *testing.T) {
+	node, nodeCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_getBalance": func(params []json.RawMessage) (interface{}, error) {
+			assert.Len(t, params, 2)
+			return "0x64", nil
+		},
+	})
+	bundler, bundlerCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_sendUserOperation": func(params []json.RawMessage) (interface{}, error) {
+			var entryPoint common.Address
+			if err := decodeParams(params, nil, &entryPoint); err != nil {
+				return nil, err
+			}
+			assert.Equal(t, common.HexToAddress(constants.ENTRY_POINT), entryPoint)
+			return "0x01", nil
+		},
+	})
+	provider, err := NewBundlerJsonRpcProvider(node.URL)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetBundlerRpc(bundler.URL))
+
+	var hash string
+	err = provider.Call(context.Background(), "eth_sendUserOperation", &hash, NewDefaultUserOperation().ToJSON(), constants.ENTRY_POINT)
+	require.NoError(t, err)
+	assert.Equal(t, "0x01", hash)
+
+	var balance hexutil.Big
+	err = provider.Call(context.Background(), "eth_getBalance", &balance, common.Address{}, "latest")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), balance.ToInt().Int64())
+
+	assert.Equal(t, []string{"eth_getBalance"}, *nodeCalls)
+	assert.Equal(t, []string{"eth_sendUserOperation"}, *bundlerCalls)
+}
+
+func TestBundlerJsonRpcProvider_BatchCall(t *testing.T) {
+	node, nodeCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_chainId":     func([]json.RawMessage) (interface{}, error) { return "0x1", nil },
+		"eth_blockNumber": func([]json.RawMessage) (interface{}, error) { return "0x200", nil },
+	})
+	bundler, bundlerCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_supportedEntryPoints": func([]json.RawMessage) (interface{}, error) {
+			return []string{constants.ENTRY_POINT}, nil
+		},
+	})
+	provider, err := NewBundlerJsonRpcProvider(node.URL)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetBundlerRpc(bundler.URL))
+
+	var chainID, blockNumber hexutil.Uint64
+	var entryPoints []common.Address
+	var receipt json.RawMessage
+	elems := []rpc.BatchElem{
+		{Method: "eth_chainId", Result: &chainID},
+		{Method: "eth_supportedEntryPoints", Result: &entryPoints},
+		{Method: "eth_blockNumber", Result: &blockNumber},
+		{Method: "eth_getUserOperationReceipt", Args: []interface{}{common.Hash{}}, Result: &receipt},
+	}
+	require.NoError(t, provider.BatchCall(context.Background(), elems))
+
+	assert.Equal(t, uint64(1), uint64(chainID))
+	assert.Equal(t, []common.Address{common.HexToAddress(constants.ENTRY_POINT)}, entryPoints)
+	assert.Equal(t, uint64(0x200), uint64(blockNumber))
+	assert.NoError(t, elems[0].Error)
+	assert.NoError(t, elems[1].Error)
+	assert.NoError(t, elems[2].Error)
+	assert.Error(t, elems[3].Error, "the bundler does not serve eth_getUserOperationReceipt")
+
+	assert.ElementsMatch(t, []string{"eth_chainId", "eth_blockNumber"}, *nodeCalls)
+	assert.ElementsMatch(t, []string{"eth_supportedEntryPoints", "eth_getUserOperationReceipt"}, *bundlerCalls)
+}
+
+func TestBundlerJsonRpcProvider_Route(t *testing.T) {
+	node, nodeCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"pm_sponsorUserOperation": func([]json.RawMessage) (interface{}, error) { return "0x02", nil },
+	})
+	bundler, bundlerCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"pimlico_getUserOperationGasPrice": func([]json.RawMessage) (interface{}, error) { return "0x03", nil },
+		"debug_bundler_dumpMempool":        func([]json.RawMessage) (interface{}, error) { return "0x04", nil },
+	})
+	paymaster, paymasterCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"pm_sponsorUserOperation": func([]json.RawMessage) (interface{}, error) { return "0x01", nil },
+	})
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		Endpoints: map[string]string{EndpointBundler: bundler.URL},
+		Routes:    map[string]string{"debug_*": EndpointBundler},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, EndpointBundler, provider.Route("eth_sendUserOperation"))
+	assert.Equal(t, EndpointBundler, provider.Route("debug_bundler_dumpMempool"))
+	assert.Equal(t, EndpointBundler, provider.Route("debug_traceCall"))
+	assert.Equal(t, EndpointPaymaster, provider.Route("pm_sponsorUserOperation"))
+	assert.Equal(t, EndpointNode, provider.Route("eth_chainId"))
+
+	var result string
+	require.NoError(t, provider.Call(context.Background(), "pm_sponsorUserOperation", &result))
+	assert.Equal(t, "0x02", result, "the paymaster endpoint is not set, so the node serves it")
+
+	require.NoError(t, provider.SetEndpoint(EndpointPaymaster, paymaster.URL))
+	require.NoError(t, provider.Call(context.Background(), "pm_sponsorUserOperation", &result))
+	assert.Equal(t, "0x01", result)
+
+	provider.RemoveRoute("pimlico_*")
+	assert.Equal(t, EndpointNode, provider.Route("pimlico_getUserOperationGasPrice"))
+	provider.SetRoute("pimlico_*", EndpointBundler)
+	require.NoError(t, provider.Call(context.Background(), "pimlico_getUserOperationGasPrice", &result))
+	assert.Equal(t, "0x03", result)
+	require.NoError(t, provider.Call(context.Background(), "debug_bundler_dumpMempool", &result))
+	assert.Equal(t, "0x04", result)
+
+	assert.Error(t, provider.SetEndpoint(EndpointNode, bundler.URL))
+	assert.Equal(t, []string{"pm_sponsorUserOperation"}, *nodeCalls)
+	assert.Equal(t, []string{"pm_sponsorUserOperation"}, *paymasterCalls)
+	assert.Equal(t, []string{"pimlico_getUserOperationGasPrice", "debug_bundler_dumpMempool"}, *bundlerCalls)
+}
+
+// testNodeService serves eth_chainId and an eth_subscribe "newHeads" feed
+// that sends the block numbers 1 and 2.
+type testNodeService struct{}
+
+func (testNodeService) ChainId() hexutil.Uint64 { return 1 }
+
+func (testNodeService) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		for i := uint64(1); i <= 2; i++ {
+			notifier.Notify(sub.ID, hexutil.Uint64(i))
+		}
+	}()
+	return sub, nil
+}
+
+func newTestNodeServer(t *testing.T) *rpc.Server {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", testNodeService{}))
+	t.Cleanup(server.Stop)
+	return server
+}
+
+func TestBundlerJsonRpcProvider_Transports(t *testing.T) {
+	ws := httptest.NewServer(newTestNodeServer(t).WebsocketHandler([]string{"*"}))
+	t.Cleanup(ws.Close)
+
+	ipcPath := filepath.Join(t.TempDir(), "node.ipc")
+	listener, err := net.Listen("unix", ipcPath)
+	require.NoError(t, err)
+	go newTestNodeServer(t).ServeListener(listener)
+
+	for name, url := range map[string]string{
+		"ws":  "ws" + strings.TrimPrefix(ws.URL, "http"),
+		"ipc": ipcPath,
+	} {
+		t.Run(name, func(t *testing.T) {
+			provider, err := NewBundlerJsonRpcProvider(url)
+			require.NoError(t, err)
+			t.Cleanup(provider.Close)
+
+			var chainID hexutil.Uint64
+			require.NoError(t, provider.Call(context.Background(), "eth_chainId", &chainID))
+			assert.Equal(t, uint64(1), uint64(chainID))
+
+			heads := make(chan hexutil.Uint64)
+			sub, err := provider.EthSubscribe(context.Background(), heads, "newHeads")
+			require.NoError(t, err)
+			defer sub.Unsubscribe()
+			for want := uint64(1); want <= 2; want++ {
+				select {
+				case head := <-heads:
+					assert.Equal(t, want, uint64(head))
+				case err := <-sub.Err():
+					t.Fatal(err)
+				case <-time.After(5 * time.Second):
+					t.Fatal("timed out waiting for newHeads")
+				}
+			}
+		})
+	}
+}
+
+// serveTestNode serves a test node on address, over WebSocket for tcp and
+// IPC for unix. The returned stop function drops every connection and closes
+// the listener, so that the node can be served again on the same address.
+func serveTestNode(t *testing.T, network, address string) (net.Addr, func()) {
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+	server := newTestNodeServer(t)
+	if network == "unix" {
+		go server.ServeListener(listener)
+	} else {
+		go http.Serve(listener, server.WebsocketHandler([]string{"*"}))
+	}
+	stop := func() {
+		listener.Close()
+		server.Stop()
+	}
+	t.Cleanup(stop)
+	return listener.Addr(), stop
+}
+
+func TestBundlerJsonRpcProvider_Reconnect(t *testing.T) {
+	for _, tc := range []struct {
+		name, network, address, scheme string
+	}{
+		{name: "ws", network: "tcp", address: "127.0.0.1:0", scheme: "ws://"},
+		{name: "ipc", network: "unix", address: filepath.Join(t.TempDir(), "node.ipc")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, stop := serveTestNode(t, tc.network, tc.address)
+			provider, err := NewBundlerJsonRpcProvider(tc.scheme + addr.String())
+			require.NoError(t, err)
+			t.Cleanup(provider.Close)
+
+			var chainID hexutil.Uint64
+			require.NoError(t, provider.Call(context.Background(), "eth_chainId", &chainID))
+			sub, err := provider.EthSubscribe(context.Background(), make(chan hexutil.Uint64, 2), "newHeads")
+			require.NoError(t, err)
+
+			stop()
+			select {
+			case err := <-sub.Err():
+				assert.Error(t, err, "the subscription ends when the socket drops")
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the subscription to end")
+			}
+
+			serveTestNode(t, tc.network, addr.String())
+			chainID = 0
+			require.NoError(t, provider.Call(context.Background(), "eth_chainId", &chainID), "the client redials the restarted node")
+			assert.Equal(t, uint64(1), uint64(chainID))
+
+			heads := make(chan hexutil.Uint64)
+			sub, err = provider.EthSubscribe(context.Background(), heads, "newHeads")
+			require.NoError(t, err, "subscriptions are re-opened by the caller")
+			defer sub.Unsubscribe()
+			select {
+			case head := <-heads:
+				assert.Equal(t, uint64(1), uint64(head))
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for newHeads")
+			}
+		})
+	}
+}
+
+func TestBundlerJsonRpcProvider_SubscribeOverHTTP(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	provider, err := NewBundlerJsonRpcProvider(node.URL)
+	require.NoError(t, err)
+
+	_, err = provider.EthSubscribe(context.Background(), make(chan hexutil.Uint64), "newHeads")
+	assert.ErrorIs(t, err, rpc.ErrNotificationsUnsupported)
+}
+
+func TestBundlerJsonRpcProvider_SetEndpointClosesPrevious(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	addr, _ := serveTestNode(t, "tcp", "127.0.0.1:0")
+	bundlerURL := "ws://" + addr.String()
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		Endpoints: map[string]string{EndpointBundler: bundlerURL},
+		Routes:    map[string]string{"eth_chainId": EndpointBundler},
+	})
+	require.NoError(t, err)
+	previous := provider.endpointFor("eth_chainId").upstreams[0].client
+
+	require.NoError(t, provider.SetEndpoints(EndpointBundler, []string{bundlerURL, bundlerURL}, nil))
+	var chainID hexutil.Uint64
+	assert.ErrorIs(t, previous.Call(&chainID, "eth_chainId"), rpc.ErrClientQuit, "the replaced client is closed")
+	assert.NoError(t, provider.Call(context.Background(), "eth_chainId", &chainID))
+
+	provider.Close()
+	for _, u := range provider.endpointFor("eth_chainId").upstreams {
+		assert.ErrorIs(t, u.client.Call(&chainID, "eth_chainId"), rpc.ErrClientQuit, "Close closes every endpoint")
+	}
+}
+
+// testNotifyService serves test_ping and reports each call on pings.
+type testNotifyService struct {
+	pings chan struct{}
+}
+
+func (s testNotifyService) Ping() { s.pings <- struct{}{} }
+
+func TestBundlerJsonRpcProvider_EmbeddedMethodsRoute(t *testing.T) {
+	node, nodeCalls := newTestRPCServer(t, map[string]rpcHandler{})
+	bundler, bundlerCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_supportedEntryPoints": func([]json.RawMessage) (interface{}, error) { return []string{constants.ENTRY_POINT}, nil },
+		"rpc_modules":              func([]json.RawMessage) (interface{}, error) { return map[string]string{"eth": "1.0"}, nil },
+	})
+	pings := make(chan struct{}, 1)
+	notifier := rpc.NewServer()
+	require.NoError(t, notifier.RegisterName("test", testNotifyService{pings: pings}))
+	t.Cleanup(notifier.Stop)
+	ws := httptest.NewServer(notifier.WebsocketHandler([]string{"*"}))
+	t.Cleanup(ws.Close)
+
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		Endpoints: map[string]string{
+			EndpointBundler: bundler.URL,
+			"notifier":      "ws" + strings.TrimPrefix(ws.URL, "http"),
+		},
+		Routes: map[string]string{"rpc_modules": EndpointBundler, "test_*": "notifier"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(provider.Close)
+
+	var entryPoints []string
+	elems := []rpc.BatchElem{{Method: "eth_supportedEntryPoints", Result: &entryPoints}}
+	require.NoError(t, provider.BatchCallContext(context.Background(), elems))
+	assert.NoError(t, elems[0].Error)
+	assert.Equal(t, []string{constants.ENTRY_POINT}, entryPoints)
+
+	modules, err := provider.SupportedModules()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"eth": "1.0"}, modules)
+
+	require.NoError(t, provider.Notify(context.Background(), "test_ping"))
+	select {
+	case <-pings:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the notification")
+	}
+
+	assert.Empty(t, *nodeCalls)
+	assert.Equal(t, []string{"eth_supportedEntryPoints", "rpc_modules"}, *bundlerCalls)
+}
+
+// headerRecorder is a RoundTripper that records the headers of each request.
+type headerRecorder struct {
+	headers []http.Header
+}
+
+func (r *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.headers = append(r.headers, req.Header.Clone())
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestBundlerJsonRpcProvider_EndpointOpts(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_chainId": func([]json.RawMessage) (interface{}, error) { return "0x1", nil },
+	})
+	bundler, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_supportedEntryPoints": func([]json.RawMessage) (interface{}, error) {
+			time.Sleep(200 * time.Millisecond)
+			return []string{constants.ENTRY_POINT}, nil
+		},
+	})
+
+	nodeRecorder, bundlerRecorder := &headerRecorder{}, &headerRecorder{}
+	var token atomic.Int64
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		Endpoints: map[string]string{EndpointBundler: bundler.URL},
+		EndpointOpts: map[string]*IEndpointOpts{
+			EndpointNode: {
+				Headers: map[string]string{"X-Api-Key": "node-key"},
+				HeaderProvider: func(header http.Header) error {
+					header.Set("Authorization", "Bearer "+hexutil.EncodeUint64(uint64(token.Add(1))))
+					return nil
+				},
+				HTTPClient: &http.Client{Transport: nodeRecorder},
+			},
+			EndpointBundler: {
+				Headers:    map[string]string{"X-Api-Key": "bundler-key"},
+				HTTPClient: &http.Client{Transport: bundlerRecorder},
+				Timeout:    20 * time.Millisecond,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var chainID hexutil.Uint64
+	require.NoError(t, provider.Call(context.Background(), "eth_chainId", &chainID))
+	require.NoError(t, provider.Call(context.Background(), "eth_chainId", &chainID))
+	require.Len(t, nodeRecorder.headers, 2)
+	assert.Equal(t, "node-key", nodeRecorder.headers[0].Get("X-Api-Key"))
+	assert.Equal(t, "Bearer 0x1", nodeRecorder.headers[0].Get("Authorization"))
+	assert.Equal(t, "Bearer 0x2", nodeRecorder.headers[1].Get("Authorization"))
+
+	var entryPoints []common.Address
+	err = provider.Call(context.Background(), "eth_supportedEntryPoints", &entryPoints)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, bundlerRecorder.headers, 1)
+	assert.Equal(t, "bundler-key", bundlerRecorder.headers[0].Get("X-Api-Key"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = provider.Call(ctx, "eth_supportedEntryPoints", &entryPoints)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "the endpoint timeout applies within a longer caller deadline")
+}
+
+// newUnavailableServer answers every request with 503 and counts them.
+func newUnavailableServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "bundler is down", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestBundlerJsonRpcProvider_Failover(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	down, downHits := newUnavailableServer(t)
+	backup, backupCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_supportedEntryPoints": func([]json.RawMessage) (interface{}, error) {
+			return []string{constants.ENTRY_POINT}, nil
+		},
+		"eth_estimateUserOperationGas": func([]json.RawMessage) (interface{}, error) {
+			return nil, errors.New("AA23 reverted")
+		},
+		"eth_getUserOperationReceipt": func([]json.RawMessage) (interface{}, error) { return nil, nil },
+	})
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		FailoverEndpoints: map[string][]string{EndpointBundler: {down.URL, backup.URL}},
+	})
+	require.NoError(t, err)
+
+	var entryPoints []common.Address
+	require.NoError(t, provider.Call(context.Background(), "eth_supportedEntryPoints", &entryPoints))
+	assert.Equal(t, []common.Address{common.HexToAddress(constants.ENTRY_POINT)}, entryPoints)
+	assert.Equal(t, int64(1), downHits.Load())
+
+	health := provider.EndpointHealth(EndpointBundler)
+	require.Len(t, health, 2)
+	assert.Equal(t, down.URL, health[0].URL)
+	assert.False(t, health[0].Healthy)
+	assert.Equal(t, 1, health[0].Failures)
+	assert.Error(t, health[0].LastError)
+	assert.True(t, health[1].Healthy)
+
+	err = provider.Call(context.Background(), "eth_estimateUserOperationGas", nil, NewDefaultUserOperation().ToJSON(), constants.ENTRY_POINT)
+	assert.ErrorContains(t, err, "AA23 reverted")
+	assert.Equal(t, int64(1), downHits.Load(), "the unhealthy bundler is tried last")
+	assert.True(t, provider.EndpointHealth(EndpointBundler)[1].Healthy, "a JSON-RPC error response is not a failure of the bundler")
+
+	var receipt json.RawMessage
+	elems := []rpc.BatchElem{{Method: "eth_getUserOperationReceipt", Args: []interface{}{common.Hash{}}, Result: &receipt}}
+	require.NoError(t, provider.BatchCall(context.Background(), elems))
+	assert.NoError(t, elems[0].Error)
+	assert.Equal(t, []string{"eth_supportedEntryPoints", "eth_estimateUserOperationGas", "eth_getUserOperationReceipt"}, *backupCalls)
+	assert.Nil(t, provider.EndpointHealth(EndpointPaymaster))
+}
+
+func TestBundlerJsonRpcProvider_FailoverExhausted(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	first, _ := newUnavailableServer(t)
+	second, _ := newUnavailableServer(t)
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		FailoverEndpoints: map[string][]string{EndpointBundler: {first.URL, second.URL}},
+	})
+	require.NoError(t, err)
+
+	var entryPoints []common.Address
+	err = provider.Call(context.Background(), "eth_supportedEntryPoints", &entryPoints)
+	var httpErr rpc.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	for _, health := range provider.EndpointHealth(EndpointBundler) {
+		assert.False(t, health.Healthy)
+	}
+}
+
+func TestBundlerJsonRpcProvider_BroadcastUserOperations(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	down, _ := newUnavailableServer(t)
+	rejecting, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_sendUserOperation": func([]json.RawMessage) (interface{}, error) {
+			return nil, errors.New("AA25 invalid account nonce")
+		},
+	})
+	accepted := make(chan struct{}, 1)
+	accepting, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_sendUserOperation": func([]json.RawMessage) (interface{}, error) {
+			accepted <- struct{}{}
+			return common.HexToHash("0x01"), nil
+		},
+	})
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		FailoverEndpoints:       map[string][]string{EndpointBundler: {down.URL, rejecting.URL, accepting.URL}},
+		BroadcastUserOperations: true,
+	})
+	require.NoError(t, err)
+
+	var hash common.Hash
+	err = provider.Call(context.Background(), "eth_sendUserOperation", &hash, NewDefaultUserOperation().ToJSON(), constants.ENTRY_POINT)
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToHash("0x01"), hash)
+	<-accepted
+
+	provider.SetBroadcastUserOperations(false)
+	err = provider.Call(context.Background(), "eth_sendUserOperation", &hash, NewDefaultUserOperation().ToJSON(), constants.ENTRY_POINT)
+	assert.ErrorContains(t, err, "AA25 invalid account nonce", "without broadcast the first bundler that answers is used")
+	assert.Empty(t, accepted)
+}
+
+// newHangingServer accepts requests and never answers them.
+func newHangingServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	var hits atomic.Int64
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-done
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+	return server, &hits
+}
+
+func TestBundlerJsonRpcProvider_FailoverHanging(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	hanging, hangingHits := newHangingServer(t)
+	backup, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_supportedEntryPoints": func([]json.RawMessage) (interface{}, error) {
+			return []string{constants.ENTRY_POINT}, nil
+		},
+	})
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		FailoverEndpoints: map[string][]string{EndpointBundler: {hanging.URL, backup.URL}},
+		EndpointOpts:      map[string]*IEndpointOpts{EndpointBundler: {Timeout: 50 * time.Millisecond}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var entryPoints []common.Address
+	require.NoError(t, provider.Call(ctx, "eth_supportedEntryPoints", &entryPoints), "a caller deadline does not stall the failover")
+	assert.Equal(t, []common.Address{common.HexToAddress(constants.ENTRY_POINT)}, entryPoints)
+	assert.Equal(t, int64(1), hangingHits.Load())
+
+	health := provider.EndpointHealth(EndpointBundler)
+	assert.False(t, health[0].Healthy)
+	assert.ErrorIs(t, health[0].LastError, context.DeadlineExceeded)
+	assert.True(t, health[1].Healthy)
+}
+
+func TestBundlerJsonRpcProvider_SendNoFailoverOnTimeout(t *testing.T) {
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	hanging, hangingHits := newHangingServer(t)
+	backup, backupCalls := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_sendUserOperation": func([]json.RawMessage) (interface{}, error) { return "0x01", nil },
+	})
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		FailoverEndpoints: map[string][]string{EndpointBundler: {hanging.URL, backup.URL}},
+		EndpointOpts:      map[string]*IEndpointOpts{EndpointBundler: {Timeout: 50 * time.Millisecond}},
+	})
+	require.NoError(t, err)
+
+	var userOpHash string
+	err = provider.CallContext(context.Background(), &userOpHash, "eth_sendUserOperation", map[string]string{}, constants.ENTRY_POINT)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(1), hangingHits.Load())
+	assert.Empty(t, *backupCalls, "a timed-out op may have been received, so it is not resent")
+
+	// The timed-out URL is unhealthy, so the next op goes to the backup.
+	require.NoError(t, provider.CallContext(context.Background(), &userOpHash, "eth_sendUserOperation", map[string]string{}, constants.ENTRY_POINT))
+	assert.Equal(t, "0x01", userOpHash)
+	assert.Equal(t, []string{"eth_sendUserOperation"}, *backupCalls)
+}
+
+func TestBundlerJsonRpcProvider_FailoverDefaultTimeout(t *testing.T) {
+	timeout := defaultFailoverTimeout
+	defaultFailoverTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { defaultFailoverTimeout = timeout })
+
+	node, _ := newTestRPCServer(t, map[string]rpcHandler{})
+	hanging, _ := newHangingServer(t)
+	backup, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_supportedEntryPoints": func([]json.RawMessage) (interface{}, error) {
+			return []string{constants.ENTRY_POINT}, nil
+		},
+	})
+	provider, err := NewBundlerJsonRpcProviderWithOpts(node.URL, &IProviderOpts{
+		FailoverEndpoints: map[string][]string{EndpointBundler: {hanging.URL, backup.URL}},
+	})
+	require.NoError(t, err)
+
+	var entryPoints []common.Address
+	require.NoError(t, provider.Call(context.Background(), "eth_supportedEntryPoints", &entryPoints))
+	assert.Equal(t, []common.Address{common.HexToAddress(constants.ENTRY_POINT)}, entryPoints)
+}
@@ -0,0 +1,39 @@
+package userop
+
+import (
+	"context"
+	"io"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// streamChannelConn adapts a StreamChannel to the reader/writer pair used by
+// rpc.DialIO. An empty message from Receive is treated as a closed stream.
+type streamChannelConn struct {
+	channel StreamChannel
+	pending []byte
+}
+
+// dialStreamChannel creates an RPC client that exchanges messages over channel.
+func dialStreamChannel(ctx context.Context, channel StreamChannel) (*rpc.Client, error) {
+	conn := &streamChannelConn{channel: channel}
+	return rpc.DialIO(ctx, conn, conn)
+}
+
+func (c *streamChannelConn) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		msg := c.channel.Receive()
+		if msg == "" {
+			return 0, io.EOF
+		}
+		c.pending = []byte(msg)
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *streamChannelConn) Write(p []byte) (int, error) {
+	c.channel.Send(string(p))
+	return len(p), nil
+}
@@ -37,6 +37,15 @@ func NewECDSAKernelFactory(client *ethclient.Client, address string) (*ECDSAKern
 	}, nil
 }
 
+// InitCode returns the EntryPoint initCode that deploys the account of owner at index
+func (f *ECDSAKernelFactory) InitCode(owner common.Address, index *big.Int) ([]byte, error) {
+	callData, err := f.contract.Pack("createAccount", owner, index)
+	if err != nil {
+		return nil, err
+	}
+	return append(f.address.Bytes(), callData...), nil
+}
+
 // CreateAccount calls the createAccount function on the contract
 func (f *ECDSAKernelFactory) CreateAccount(owner common.Address, index *big.Int, opts *bind.TransactOpts) (common.Address, error) {
 	callData, err := f.contract.Pack("createAccount", owner, index)
@@ -54,7 +63,7 @@ func (f *ECDSAKernelFactory) CreateAccount(owner common.Address, index *big.Int,
 		return common.Address{}, err
 	}
 
-	receipt, err := bind.WaitMined(context.Background(), f.client, tx)
+	receipt, err := bind.WaitMined(context.Background(), f.client, signedTx)
 	if err != nil {
 		return common.Address{}, err
 	}
@@ -76,70 +85,39 @@ func (f *ECDSAKernelFactory) CreateAccount(owner common.Address, index *big.Int,
 
 // EntryPoint returns the entry point address
 func (f *ECDSAKernelFactory) EntryPoint() (common.Address, error) {
-	var result []interface{}
-	msg := ethereum.CallMsg{
-		To:   &f.address,
-		Data: f.contract.Methods["entryPoint"].ID,
-	}
-	output, err := f.client.CallContract(context.Background(), msg, nil)
-	if err != nil {
-		return common.Address{}, err
-	}
-	err = f.contract.UnpackIntoInterface(&result, "entryPoint", output)
-	if err != nil {
-		return common.Address{}, err
-	}
-	return result[0].(common.Address), nil
+	return f.callAddress("entryPoint")
 }
 
 // GetAccountAddress returns the account address based on owner and index
 func (f *ECDSAKernelFactory) GetAccountAddress(owner common.Address, index *big.Int) (common.Address, error) {
-	var result []interface{}
-	msg := ethereum.CallMsg{
-		To:   &f.address,
-		Data: f.contract.Methods["getAccountAddress"].ID,
-	}
-	output, err := f.client.CallContract(context.Background(), msg, nil)
-	if err != nil {
-		return common.Address{}, err
-	}
-	err = f.contract.UnpackIntoInterface(&result, "getAccountAddress", output)
-	if err != nil {
-		return common.Address{}, err
-	}
-	return result[0].(common.Address), nil
+	return f.callAddress("getAccountAddress", owner, index)
 }
 
 // SingletonFactory returns the singleton factory address
 func (f *ECDSAKernelFactory) SingletonFactory() (common.Address, error) {
-	var result []interface{}
-	msg := ethereum.CallMsg{
-		To:   &f.address,
-		Data: f.contract.Methods["singletonFactory"].ID,
-	}
-	output, err := f.client.CallContract(context.Background(), msg, nil)
-	if err != nil {
-		return common.Address{}, err
-	}
-	err = f.contract.UnpackIntoInterface(&result, "singletonFactory", output)
-	if err != nil {
-		return common.Address{}, err
-	}
-	return result[0].(common.Address), nil
+	return f.callAddress("singletonFactory")
 }
 
 // Validator returns the validator address
 func (f *ECDSAKernelFactory) Validator() (common.Address, error) {
-	var result []interface{}
+	return f.callAddress("validator")
+}
+
+// callAddress calls a view method of the factory that returns a single address
+func (f *ECDSAKernelFactory) callAddress(method string, args ...interface{}) (common.Address, error) {
+	data, err := f.contract.Pack(method, args...)
+	if err != nil {
+		return common.Address{}, err
+	}
 	msg := ethereum.CallMsg{
 		To:   &f.address,
-		Data: f.contract.Methods["validator"].ID,
+		Data: data,
 	}
 	output, err := f.client.CallContract(context.Background(), msg, nil)
 	if err != nil {
 		return common.Address{}, err
 	}
-	err = f.contract.UnpackIntoInterface(&result, "validator", output)
+	result, err := f.contract.Unpack(method, output)
 	if err != nil {
 		return common.Address{}, err
 	}
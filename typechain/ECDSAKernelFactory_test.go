@@ -1,59 +1,115 @@
 package typechain
 
 import (
-	"context"
+	"encoding/json"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
-	// Adjust the import path to your project
+	"github.com/stretchr/testify/require"
 )
 
-// MockEthClient is a mock implementation of ethclient.Client
-type MockEthClient struct {
-	mock.Mock
-	ethclient.Client
-}
-
-func (m *MockEthClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
-	args := m.Called(ctx, call, blockNumber)
-	return args.Get(0).([]byte), args.Error(1)
-}
-
-func (m *MockEthClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	args := m.Called(ctx, tx)
-	return args.Error(0)
-}
-
-func (m *MockEthClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
-	return nil, nil
+// newTestKernelFactory binds an ECDSAKernelFactory to a fake node. Each
+// eth_call returns the outputs of the called view method packed from
+// results, and sent transactions are mined at once with a log whose data is
+// the packed createAccount output.
+func newTestKernelFactory(t *testing.T, results map[string]common.Address, sent *[]*types.Transaction) *ECDSAKernelFactory {
+	t.Helper()
+	factory, err := NewECDSAKernelFactory(nil, "0x5de4839a76cf55d0c90e2061ef4386d962E15ae3")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_call":
+			var msg struct {
+				Input hexutil.Bytes `json:"input"`
+			}
+			if !assert.NoError(t, json.Unmarshal(req.Params[0], &msg)) {
+				return
+			}
+			method, err := factory.contract.MethodById(msg.Input)
+			if !assert.NoError(t, err) {
+				return
+			}
+			output, err := method.Outputs.Pack(results[method.Name])
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp["result"] = hexutil.Encode(output)
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			if !assert.NoError(t, json.Unmarshal(req.Params[0], &raw)) {
+				return
+			}
+			tx := new(types.Transaction)
+			if !assert.NoError(t, tx.UnmarshalBinary(raw)) {
+				return
+			}
+			*sent = append(*sent, tx)
+			resp["result"] = tx.Hash()
+		case "eth_getTransactionReceipt":
+			var hash common.Hash
+			if !assert.NoError(t, json.Unmarshal(req.Params[0], &hash)) {
+				return
+			}
+			output, err := factory.contract.Methods["createAccount"].Outputs.Pack(results["createAccount"])
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp["result"] = &types.Receipt{
+				Status: types.ReceiptStatusSuccessful,
+				TxHash: hash,
+				Logs:   []*types.Log{{Address: factory.address, Topics: []common.Hash{}, Data: output, TxHash: hash}},
+			}
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	require.NoError(t, err)
+	factory.client = client
+	return factory
 }
 
 func TestNewECDSAKernelFactory(t *testing.T) {
-	mockClient := new(MockEthClient)
-	address := "0xYourContractAddress"
+	address := "0x5de4839a76cf55d0c90e2061ef4386d962E15ae3"
 
-	factory, err := NewECDSAKernelFactory(&mockClient.Client, address)
+	factory, err := NewECDSAKernelFactory(nil, address)
 	assert.NoError(t, err)
 	assert.NotNil(t, factory)
 	assert.Equal(t, common.HexToAddress(address), factory.address)
 }
 
 func TestECDSAKernelFactory_CreateAccount(t *testing.T) {
-	mockClient := new(MockEthClient)
-	factory, err := NewECDSAKernelFactory(&mockClient.Client, "0xYourContractAddress")
-	assert.NoError(t, err)
+	expectedAccountAddress := common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72")
+	var sent []*types.Transaction
+	factory := newTestKernelFactory(t, map[string]common.Address{"createAccount": expectedAccountAddress}, &sent)
 
-	owner := common.HexToAddress("0xOwnerAddress")
+	owner := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
 	index := big.NewInt(1)
 	opts := &bind.TransactOpts{
-		From:     common.HexToAddress("0xFromAddress"),
+		From:     common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
 		GasPrice: big.NewInt(1000),
 		GasLimit: uint64(3000000),
 		Nonce:    big.NewInt(0),
@@ -62,72 +118,50 @@ func TestECDSAKernelFactory_CreateAccount(t *testing.T) {
 		},
 	}
 
-	mockClient.On("SendTransaction", mock.Anything, mock.Anything).Return(nil)
-
 	address, err := factory.CreateAccount(owner, index, opts)
-	assert.NoError(t, err)
-	assert.NotEmpty(t, address)
-	mockClient.AssertExpectations(t)
+	require.NoError(t, err)
+	assert.Equal(t, expectedAccountAddress, address)
+	require.Len(t, sent, 1)
+	assert.Equal(t, factory.address, *sent[0].To())
+	callData, err := factory.contract.Pack("createAccount", owner, index)
+	require.NoError(t, err)
+	assert.Equal(t, callData, sent[0].Data())
 }
 
 func TestECDSAKernelFactory_EntryPoint(t *testing.T) {
-	mockClient := new(MockEthClient)
-	factory, err := NewECDSAKernelFactory(&mockClient.Client, "0xYourContractAddress")
-	assert.NoError(t, err)
-
-	expectedEntryPoint := common.HexToAddress("0xExpectedEntryPoint")
-	mockClient.On("CallContract", mock.Anything, mock.Anything, mock.Anything).
-		Return([]byte(expectedEntryPoint.Bytes()), nil)
+	expectedEntryPoint := common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+	factory := newTestKernelFactory(t, map[string]common.Address{"entryPoint": expectedEntryPoint}, nil)
 
 	entryPoint, err := factory.EntryPoint()
 	assert.NoError(t, err)
 	assert.Equal(t, expectedEntryPoint, entryPoint)
-	mockClient.AssertExpectations(t)
 }
 
 func TestECDSAKernelFactory_GetAccountAddress(t *testing.T) {
-	mockClient := new(MockEthClient)
-	factory, err := NewECDSAKernelFactory(&mockClient.Client, "0xYourContractAddress")
-	assert.NoError(t, err)
+	expectedAccountAddress := common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72")
+	factory := newTestKernelFactory(t, map[string]common.Address{"getAccountAddress": expectedAccountAddress}, nil)
 
-	owner := common.HexToAddress("0xOwnerAddress")
+	owner := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
 	index := big.NewInt(1)
-	expectedAccountAddress := common.HexToAddress("0xExpectedAccountAddress")
-	mockClient.On("CallContract", mock.Anything, mock.Anything, mock.Anything).
-		Return([]byte(expectedAccountAddress.Bytes()), nil)
-
 	accountAddress, err := factory.GetAccountAddress(owner, index)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedAccountAddress, accountAddress)
-	mockClient.AssertExpectations(t)
 }
 
 func TestECDSAKernelFactory_SingletonFactory(t *testing.T) {
-	mockClient := new(MockEthClient)
-	factory, err := NewECDSAKernelFactory(&mockClient.Client, "0xYourContractAddress")
-	assert.NoError(t, err)
-
-	expectedSingletonFactory := common.HexToAddress("0xExpectedSingletonFactory")
-	mockClient.On("CallContract", mock.Anything, mock.Anything, mock.Anything).
-		Return([]byte(expectedSingletonFactory.Bytes()), nil)
+	expectedSingletonFactory := common.HexToAddress("0x4E4946298614FC299B50c947289F4aD0572CB9ce")
+	factory := newTestKernelFactory(t, map[string]common.Address{"singletonFactory": expectedSingletonFactory}, nil)
 
 	singletonFactory, err := factory.SingletonFactory()
 	assert.NoError(t, err)
 	assert.Equal(t, expectedSingletonFactory, singletonFactory)
-	mockClient.AssertExpectations(t)
 }
 
 func TestECDSAKernelFactory_Validator(t *testing.T) {
-	mockClient := new(MockEthClient)
-	factory, err := NewECDSAKernelFactory(&mockClient.Client, "0xYourContractAddress")
-	assert.NoError(t, err)
-
-	expectedValidator := common.HexToAddress("0xExpectedValidator")
-	mockClient.On("CallContract", mock.Anything, mock.Anything, mock.Anything).
-		Return([]byte(expectedValidator.Bytes()), nil)
+	expectedValidator := common.HexToAddress("0x180D6465F921C7E0DEA0040107D342c87455fFF5")
+	factory := newTestKernelFactory(t, map[string]common.Address{"validator": expectedValidator}, nil)
 
 	validator, err := factory.Validator()
 	assert.NoError(t, err)
 	assert.Equal(t, expectedValidator, validator)
-	mockClient.AssertExpectations(t)
 }
@@ -1,14 +1,75 @@
 package typechain
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// UserOperation is the v0.6 UserOperation struct as encoded in EntryPoint calls
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// DepositInfo is the deposit and stake of an account held by the EntryPoint
+type DepositInfo struct {
+	Deposit         *big.Int
+	Staked          bool
+	Stake           *big.Int
+	UnstakeDelaySec uint32
+	WithdrawTime    *big.Int
+}
+
+// ValidationResult is the decoded result of simulateValidation.
+// AggregatorInfo is only set when the account uses a signature aggregator.
+type ValidationResult struct {
+	ReturnInfo     ReturnInfo
+	SenderInfo     StakeInfo
+	FactoryInfo    StakeInfo
+	PaymasterInfo  StakeInfo
+	AggregatorInfo *AggregatorStakeInfo
+}
+
+// ExecutionResult is the decoded result of simulateHandleOp
+type ExecutionResult struct {
+	PreOpGas      *big.Int
+	Paid          *big.Int
+	ValidAfter    *big.Int
+	ValidUntil    *big.Int
+	TargetSuccess bool
+	TargetResult  []byte
+}
+
+// UnpackTargetResult decodes TargetResult as the outputs of method in contractABI.
+// It fails if the target call reverted.
+func (r *ExecutionResult) UnpackTargetResult(contractABI abi.ABI, method string) ([]interface{}, error) {
+	if !r.TargetSuccess {
+		return nil, fmt.Errorf("target call reverted: %s", hexutil.Encode(r.TargetResult))
+	}
+	return contractABI.Unpack(method, r.TargetResult)
+}
+
 type EntryPoint struct {
 	contractABI     *abi.ABI
 	contractAddress common.Address
@@ -20,7 +81,12 @@ const EntryPointContract = `[{"inputs":[{"internalType":"uint256","name":"preOpG
 
 // Create a new EntryPoint instance
 func NewEntryPoint(address common.Address, client *rpc.Client, chainId *big.Int) (*EntryPoint, error) {
-	contractABI, err := abi.JSON(strings.NewReader(string(EntryPointContract)))
+	return newEntryPoint(EntryPointContract, address, client, chainId)
+}
+
+// newEntryPoint binds the EntryPoint at address with the given contract ABI
+func newEntryPoint(contract string, address common.Address, client *rpc.Client, chainId *big.Int) (*EntryPoint, error) {
+	contractABI, err := abi.JSON(strings.NewReader(contract))
 	if err != nil {
 		return nil, err
 	}
@@ -32,3 +98,190 @@ func NewEntryPoint(address common.Address, client *rpc.Client, chainId *big.Int)
 		chainId:         chainId,
 	}, nil
 }
+
+// GetNonce returns the nonce of sender for the given nonce key
+func (e *EntryPoint) GetNonce(ctx context.Context, sender common.Address, key *big.Int) (*big.Int, error) {
+	output, err := e.call(ctx, "getNonce", sender, key)
+	if err != nil {
+		return nil, err
+	}
+	results, err := e.contractABI.Unpack("getNonce", output)
+	if err != nil {
+		return nil, err
+	}
+	return results[0].(*big.Int), nil
+}
+
+// GetSenderAddress returns the counterfactual address of the account created by initCode.
+// The EntryPoint always reverts with SenderAddressResult, which is decoded here.
+func (e *EntryPoint) GetSenderAddress(ctx context.Context, initCode []byte) (common.Address, error) {
+	_, err := e.call(ctx, "getSenderAddress", initCode)
+	if err == nil {
+		return common.Address{}, fmt.Errorf("getSenderAddress: unexpected result")
+	}
+
+	var result *SenderAddressResultError
+	if !errors.As(DecodeEntryPointError(err), &result) {
+		return common.Address{}, err
+	}
+	return result.Sender, nil
+}
+
+// SimulateValidation runs the validation of op with eth_call. The EntryPoint
+// always reverts, with ValidationResult or ValidationResultWithAggregation on
+// success; any other revert is returned as a typed error such as *FailedOpError.
+func (e *EntryPoint) SimulateValidation(ctx context.Context, op UserOperation) (*ValidationResult, error) {
+	_, err := e.call(ctx, "simulateValidation", op)
+	if err == nil {
+		return nil, fmt.Errorf("simulateValidation: unexpected result")
+	}
+
+	switch result := DecodeEntryPointError(err).(type) {
+	case *ValidationResultError:
+		return &ValidationResult{
+			ReturnInfo:    result.ReturnInfo,
+			SenderInfo:    result.SenderInfo,
+			FactoryInfo:   result.FactoryInfo,
+			PaymasterInfo: result.PaymasterInfo,
+		}, nil
+	case *ValidationResultWithAggregationError:
+		return &ValidationResult{
+			ReturnInfo:     result.ReturnInfo,
+			SenderInfo:     result.SenderInfo,
+			FactoryInfo:    result.FactoryInfo,
+			PaymasterInfo:  result.PaymasterInfo,
+			AggregatorInfo: &result.AggregatorInfo,
+		}, nil
+	default:
+		return nil, result
+	}
+}
+
+// SimulateHandleOp executes op with eth_call and then calls target with
+// targetCallData, so the state after the op can be inspected. Pass the zero
+// address as target to skip the target call. The EntryPoint always reverts,
+// with ExecutionResult on success; any other revert is returned as a typed
+// error such as *FailedOpError.
+func (e *EntryPoint) SimulateHandleOp(ctx context.Context, op UserOperation, target common.Address, targetCallData []byte) (*ExecutionResult, error) {
+	if targetCallData == nil {
+		targetCallData = []byte{}
+	}
+	_, err := e.call(ctx, "simulateHandleOp", op, target, targetCallData)
+	if err == nil {
+		return nil, fmt.Errorf("simulateHandleOp: unexpected result")
+	}
+
+	decoded := DecodeEntryPointError(err)
+	result, ok := decoded.(*ExecutionResultError)
+	if !ok {
+		return nil, decoded
+	}
+	return &ExecutionResult{
+		PreOpGas:      result.PreOpGas,
+		Paid:          result.Paid,
+		ValidAfter:    result.ValidAfter,
+		ValidUntil:    result.ValidUntil,
+		TargetSuccess: result.TargetSuccess,
+		TargetResult:  result.TargetResult,
+	}, nil
+}
+
+// GetUserOpHash returns the hash of op as computed by the EntryPoint
+func (e *EntryPoint) GetUserOpHash(ctx context.Context, op UserOperation) (common.Hash, error) {
+	output, err := e.call(ctx, "getUserOpHash", op)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	results, err := e.contractABI.Unpack("getUserOpHash", output)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return results[0].([32]byte), nil
+}
+
+// BalanceOf returns the deposit of account
+func (e *EntryPoint) BalanceOf(ctx context.Context, account common.Address) (*big.Int, error) {
+	output, err := e.call(ctx, "balanceOf", account)
+	if err != nil {
+		return nil, err
+	}
+	results, err := e.contractABI.Unpack("balanceOf", output)
+	if err != nil {
+		return nil, err
+	}
+	return results[0].(*big.Int), nil
+}
+
+// GetDepositInfo returns the deposit and stake information of account
+func (e *EntryPoint) GetDepositInfo(ctx context.Context, account common.Address) (*DepositInfo, error) {
+	output, err := e.call(ctx, "getDepositInfo", account)
+	if err != nil {
+		return nil, err
+	}
+	results, err := e.contractABI.Unpack("getDepositInfo", output)
+	if err != nil {
+		return nil, err
+	}
+	return abi.ConvertType(results[0], new(DepositInfo)).(*DepositInfo), nil
+}
+
+// DepositTo adds opts.Value to the deposit of account
+func (e *EntryPoint) DepositTo(opts *bind.TransactOpts, account common.Address) (*types.Transaction, error) {
+	return e.transact(opts, "depositTo", account)
+}
+
+// WithdrawTo withdraws amount from the deposit of opts.From to withdrawAddress
+func (e *EntryPoint) WithdrawTo(opts *bind.TransactOpts, withdrawAddress common.Address, amount *big.Int) (*types.Transaction, error) {
+	return e.transact(opts, "withdrawTo", withdrawAddress, amount)
+}
+
+// AddStake adds opts.Value to the stake of opts.From with the given unstake delay
+func (e *EntryPoint) AddStake(opts *bind.TransactOpts, unstakeDelaySec uint32) (*types.Transaction, error) {
+	return e.transact(opts, "addStake", unstakeDelaySec)
+}
+
+// UnlockStake starts the unstake delay of opts.From
+func (e *EntryPoint) UnlockStake(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return e.transact(opts, "unlockStake")
+}
+
+// WithdrawStake withdraws the unlocked stake of opts.From to withdrawAddress
+func (e *EntryPoint) WithdrawStake(opts *bind.TransactOpts, withdrawAddress common.Address) (*types.Transaction, error) {
+	return e.transact(opts, "withdrawStake", withdrawAddress)
+}
+
+// call packs method with args and executes it with eth_call against the latest block
+func (e *EntryPoint) call(ctx context.Context, method string, args ...interface{}) ([]byte, error) {
+	data, err := e.contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	msg := ethereum.CallMsg{
+		To:   &e.contractAddress,
+		Data: data,
+	}
+	return ethclient.NewClient(e.client).CallContract(ctx, msg, nil)
+}
+
+// transact packs method with args and sends it as a transaction signed by opts
+func (e *EntryPoint) transact(opts *bind.TransactOpts, method string, args ...interface{}) (*types.Transaction, error) {
+	client := ethclient.NewClient(e.client)
+	return bind.NewBoundContract(e.contractAddress, *e.contractABI, client, client, client).Transact(opts, method, args...)
+}
+
+// revertData extracts the revert data from a JSON-RPC error, if present
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,198 @@
+package typechain
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// entryPointABIs are the parsed EntryPoint ABIs, used to decode revert data
+var (
+	entryPointABI    = mustParseABI(EntryPointContract)
+	entryPointV07ABI = mustParseABI(EntryPointV07Contract)
+)
+
+func mustParseABI(contract string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(contract))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// ReturnInfo is the gas and validity information returned by simulateValidation
+type ReturnInfo struct {
+	PreOpGas         *big.Int
+	Prefund          *big.Int
+	SigFailed        bool
+	ValidAfter       *big.Int
+	ValidUntil       *big.Int
+	PaymasterContext []byte
+}
+
+// StakeInfo is the stake of an entity taking part in validation
+type StakeInfo struct {
+	Stake           *big.Int
+	UnstakeDelaySec *big.Int
+}
+
+// AggregatorStakeInfo is the signature aggregator and its stake
+type AggregatorStakeInfo struct {
+	Aggregator common.Address
+	StakeInfo  StakeInfo
+}
+
+// FailedOpError is returned by handleOps and the simulation methods when an op fails validation
+type FailedOpError struct {
+	OpIndex *big.Int
+	Reason  string
+}
+
+func (e *FailedOpError) Error() string {
+	return fmt.Sprintf("FailedOp(%s, %q)", e.OpIndex, e.Reason)
+}
+
+// FailedOpWithRevertError is returned by EntryPoint v0.7 when an op fails
+// validation because the account, factory or paymaster reverted
+type FailedOpWithRevertError struct {
+	OpIndex *big.Int
+	Reason  string
+	Inner   []byte
+}
+
+func (e *FailedOpWithRevertError) Error() string {
+	return fmt.Sprintf("FailedOpWithRevert(%s, %q, %s)", e.OpIndex, e.Reason, hexutil.Encode(e.Inner))
+}
+
+// SignatureValidationFailedError is returned when an aggregator rejects a signature
+type SignatureValidationFailedError struct {
+	Aggregator common.Address
+}
+
+func (e *SignatureValidationFailedError) Error() string {
+	return fmt.Sprintf("SignatureValidationFailed(%s)", e.Aggregator.Hex())
+}
+
+// ValidationResultError is the successful result of simulateValidation
+type ValidationResultError struct {
+	ReturnInfo    ReturnInfo
+	SenderInfo    StakeInfo
+	FactoryInfo   StakeInfo
+	PaymasterInfo StakeInfo
+}
+
+func (e *ValidationResultError) Error() string {
+	return fmt.Sprintf("ValidationResult(preOpGas=%s, prefund=%s, sigFailed=%t)", e.ReturnInfo.PreOpGas, e.ReturnInfo.Prefund, e.ReturnInfo.SigFailed)
+}
+
+// ValidationResultWithAggregationError is the successful result of
+// simulateValidation for an op that uses a signature aggregator
+type ValidationResultWithAggregationError struct {
+	ReturnInfo     ReturnInfo
+	SenderInfo     StakeInfo
+	FactoryInfo    StakeInfo
+	PaymasterInfo  StakeInfo
+	AggregatorInfo AggregatorStakeInfo
+}
+
+func (e *ValidationResultWithAggregationError) Error() string {
+	return fmt.Sprintf("ValidationResultWithAggregation(preOpGas=%s, prefund=%s, sigFailed=%t, aggregator=%s)", e.ReturnInfo.PreOpGas, e.ReturnInfo.Prefund, e.ReturnInfo.SigFailed, e.AggregatorInfo.Aggregator.Hex())
+}
+
+// ExecutionResultError is the successful result of simulateHandleOp
+type ExecutionResultError struct {
+	PreOpGas      *big.Int
+	Paid          *big.Int
+	ValidAfter    *big.Int
+	ValidUntil    *big.Int
+	TargetSuccess bool
+	TargetResult  []byte
+}
+
+func (e *ExecutionResultError) Error() string {
+	return fmt.Sprintf("ExecutionResult(preOpGas=%s, paid=%s, targetSuccess=%t)", e.PreOpGas, e.Paid, e.TargetSuccess)
+}
+
+// SenderAddressResultError is the result of getSenderAddress
+type SenderAddressResultError struct {
+	Sender common.Address
+}
+
+func (e *SenderAddressResultError) Error() string {
+	return fmt.Sprintf("SenderAddressResult(%s)", e.Sender.Hex())
+}
+
+// DecodeEntryPointError decodes the revert data carried by a JSON-RPC error
+// into one of the EntryPoint error types, so it can be matched with errors.As.
+// Errors without EntryPoint revert data are returned unchanged.
+func DecodeEntryPointError(err error) error {
+	if err == nil {
+		return nil
+	}
+	data, ok := revertData(err)
+	if !ok {
+		return err
+	}
+	decoded, ok := decodeEntryPointRevert(data)
+	if !ok {
+		return err
+	}
+	return decoded
+}
+
+// decodeEntryPointRevert decodes data into the matching EntryPoint error type
+func decodeEntryPointRevert(data []byte) (error, bool) {
+	if len(data) < 4 {
+		return nil, false
+	}
+
+	for _, contractABI := range []abi.ABI{entryPointABI, entryPointV07ABI} {
+		if decoded, ok := decodeRevert(contractABI, data); ok {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+// decodeRevert decodes data with the errors declared in contractABI
+func decodeRevert(contractABI abi.ABI, data []byte) (error, bool) {
+	var target error
+	for name, abiError := range contractABI.Errors {
+		if !bytes.Equal(data[:4], abiError.ID[:4]) {
+			continue
+		}
+		switch name {
+		case "FailedOp":
+			target = new(FailedOpError)
+		case "FailedOpWithRevert":
+			target = new(FailedOpWithRevertError)
+		case "SignatureValidationFailed":
+			target = new(SignatureValidationFailedError)
+		case "ValidationResult":
+			target = new(ValidationResultError)
+		case "ValidationResultWithAggregation":
+			target = new(ValidationResultWithAggregationError)
+		case "ExecutionResult":
+			target = new(ExecutionResultError)
+		case "SenderAddressResult":
+			target = new(SenderAddressResultError)
+		default:
+			return nil, false
+		}
+
+		values, err := abiError.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, false
+		}
+		if err := abiError.Inputs.Copy(target, values); err != nil {
+			return nil, false
+		}
+		return target, true
+	}
+	return nil, false
+}
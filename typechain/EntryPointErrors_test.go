@@ -0,0 +1,92 @@
+package typechain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revertError is a JSON-RPC error carrying revert data, as returned by eth_call.
+type revertError struct {
+	data string
+}
+
+func (e *revertError) Error() string          { return "execution reverted" }
+func (e *revertError) ErrorData() interface{} { return e.data }
+
+// packRevert encodes args as the revert data of the named EntryPoint error.
+func packRevert(t *testing.T, name string, args ...interface{}) error {
+	t.Helper()
+	abiError := entryPointABI.Errors[name]
+	data, err := abiError.Inputs.Pack(args...)
+	require.NoError(t, err)
+	return &revertError{data: hexutil.Encode(append(abiError.ID[:4], data...))}
+}
+
+func TestDecodeEntryPointError_FailedOp(t *testing.T) {
+	err := DecodeEntryPointError(packRevert(t, "FailedOp", big.NewInt(0), "AA21 didn't pay prefund"))
+
+	var failedOp *FailedOpError
+	require.True(t, errors.As(err, &failedOp))
+	assert.Equal(t, int64(0), failedOp.OpIndex.Int64())
+	assert.Equal(t, "AA21 didn't pay prefund", failedOp.Reason)
+}
+
+func TestDecodeEntryPointError_ValidationResult(t *testing.T) {
+	returnInfo := ReturnInfo{
+		PreOpGas:         big.NewInt(50000),
+		Prefund:          big.NewInt(1e15),
+		SigFailed:        true,
+		ValidAfter:       big.NewInt(0),
+		ValidUntil:       big.NewInt(1700000000),
+		PaymasterContext: []byte{},
+	}
+	stake := StakeInfo{Stake: big.NewInt(1), UnstakeDelaySec: big.NewInt(86400)}
+	err := DecodeEntryPointError(packRevert(t, "ValidationResult", returnInfo, stake, stake, stake))
+
+	var result *ValidationResultError
+	require.True(t, errors.As(err, &result))
+	assert.Equal(t, int64(50000), result.ReturnInfo.PreOpGas.Int64())
+	assert.Equal(t, int64(1e15), result.ReturnInfo.Prefund.Int64())
+	assert.True(t, result.ReturnInfo.SigFailed)
+	assert.Equal(t, int64(1700000000), result.ReturnInfo.ValidUntil.Int64())
+	assert.Equal(t, int64(86400), result.PaymasterInfo.UnstakeDelaySec.Int64())
+}
+
+func TestDecodeEntryPointError_SignatureValidationFailed(t *testing.T) {
+	aggregator := common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72")
+	err := DecodeEntryPointError(packRevert(t, "SignatureValidationFailed", aggregator))
+
+	var failed *SignatureValidationFailedError
+	require.True(t, errors.As(err, &failed))
+	assert.Equal(t, aggregator, failed.Aggregator)
+}
+
+func TestDecodeEntryPointError_Passthrough(t *testing.T) {
+	assert.NoError(t, DecodeEntryPointError(nil))
+
+	plain := errors.New("connection refused")
+	assert.Equal(t, plain, DecodeEntryPointError(plain))
+
+	// Error(string) reverts are not EntryPoint errors.
+	unknown := &revertError{data: "0x08c379a0"}
+	assert.Equal(t, error(unknown), DecodeEntryPointError(unknown))
+}
+
+func TestDecodeEntryPointError_FailedOpWithRevert(t *testing.T) {
+	abiError := entryPointV07ABI.Errors["FailedOpWithRevert"]
+	data, err := abiError.Inputs.Pack(big.NewInt(1), "AA23 reverted", []byte{0x01, 0x02})
+	require.NoError(t, err)
+	decoded := DecodeEntryPointError(&revertError{data: hexutil.Encode(append(abiError.ID[:4], data...))})
+
+	var failedOp *FailedOpWithRevertError
+	require.True(t, errors.As(decoded, &failedOp))
+	assert.Equal(t, int64(1), failedOp.OpIndex.Int64())
+	assert.Equal(t, "AA23 reverted", failedOp.Reason)
+	assert.Equal(t, []byte{0x01, 0x02}, failedOp.Inner)
+}
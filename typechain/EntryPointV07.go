@@ -0,0 +1,102 @@
+package typechain
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EntryPointV07Contract is the ABI of EntryPoint v0.7
+const EntryPointV07Contract = `[{"inputs":[{"internalType":"uint256","name":"opIndex","type":"uint256"},{"internalType":"string","name":"reason","type":"string"}],"name":"FailedOp","type":"error"},{"inputs":[{"internalType":"uint256","name":"opIndex","type":"uint256"},{"internalType":"string","name":"reason","type":"string"},{"internalType":"bytes","name":"inner","type":"bytes"}],"name":"FailedOpWithRevert","type":"error"},{"inputs":[{"internalType":"bytes","name":"returnData","type":"bytes"}],"name":"PostOpReverted","type":"error"},{"inputs":[{"internalType":"address","name":"sender","type":"address"}],"name":"SenderAddressResult","type":"error"},{"inputs":[{"internalType":"address","name":"aggregator","type":"address"}],"name":"SignatureValidationFailed","type":"error"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"bytes32","name":"userOpHash","type":"bytes32"},{"indexed":true,"internalType":"address","name":"sender","type":"address"},{"indexed":false,"internalType":"address","name":"factory","type":"address"},{"indexed":false,"internalType":"address","name":"paymaster","type":"address"}],"name":"AccountDeployed","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"account","type":"address"},{"indexed":false,"internalType":"uint256","name":"totalDeposit","type":"uint256"}],"name":"Deposited","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"bytes32","name":"userOpHash","type":"bytes32"},{"indexed":true,"internalType":"address","name":"sender","type":"address"},{"indexed":false,"internalType":"uint256","name":"nonce","type":"uint256"},{"indexed":false,"internalType":"bytes","name":"revertReason","type":"bytes"}],"name":"PostOpRevertReason","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"bytes32","name":"userOpHash","type":"bytes32"},{"indexed":true,"internalType":"address","name":"sender","type":"address"},{"indexed":true,"internalType":"address","name":"paymaster","type":"address"},{"indexed":false,"internalType":"uint256","name":"nonce","type":"uint256"},{"indexed":false,"internalType":"bool","name":"success","type":"bool"},{"indexed":false,"internalType":"uint256","name":"actualGasCost","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"actualGasUsed","type":"uint256"}],"name":"UserOperationEvent","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"bytes32","name":"userOpHash","type":"bytes32"},{"indexed":true,"internalType":"address","name":"sender","type":"address"},{"indexed":false,"internalType":"uint256","name":"nonce","type":"uint256"}],"name":"UserOperationPrefundTooLow","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"bytes32","name":"userOpHash","type":"bytes32"},{"indexed":true,"internalType":"address","name":"sender","type":"address"},{"indexed":false,"internalType":"uint256","name":"nonce","type":"uint256"},{"indexed":false,"internalType":"bytes","name":"revertReason","type":"bytes"}],"name":"UserOperationRevertReason","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"account","type":"address"},{"indexed":false,"internalType":"address","name":"withdrawAddress","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"}],"name":"Withdrawn","type":"event"},{"inputs":[{"internalType":"uint32","name":"unstakeDelaySec","type":"uint32"}],"name":"addStake","outputs":[],"stateMutability":"payable","type":"function"},{"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"balanceOf","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"depositTo","outputs":[],"stateMutability":"payable","type":"function"},{"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"getDepositInfo","outputs":[{"components":[{"internalType":"uint256","name":"deposit","type":"uint256"},{"internalType":"bool","name":"staked","type":"bool"},{"internalType":"uint112","name":"stake","type":"uint112"},{"internalType":"uint32","name":"unstakeDelaySec","type":"uint32"},{"internalType":"uint48","name":"withdrawTime","type":"uint48"}],"internalType":"struct IStakeManager.DepositInfo","name":"info","type":"tuple"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"sender","type":"address"},{"internalType":"uint192","name":"key","type":"uint192"}],"name":"getNonce","outputs":[{"internalType":"uint256","name":"nonce","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"bytes","name":"initCode","type":"bytes"}],"name":"getSenderAddress","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"components":[{"internalType":"address","name":"sender","type":"address"},{"internalType":"uint256","name":"nonce","type":"uint256"},{"internalType":"bytes","name":"initCode","type":"bytes"},{"internalType":"bytes","name":"callData","type":"bytes"},{"internalType":"bytes32","name":"accountGasLimits","type":"bytes32"},{"internalType":"uint256","name":"preVerificationGas","type":"uint256"},{"internalType":"bytes32","name":"gasFees","type":"bytes32"},{"internalType":"bytes","name":"paymasterAndData","type":"bytes"},{"internalType":"bytes","name":"signature","type":"bytes"}],"internalType":"struct PackedUserOperation","name":"userOp","type":"tuple"}],"name":"getUserOpHash","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},{"inputs":[{"components":[{"internalType":"address","name":"sender","type":"address"},{"internalType":"uint256","name":"nonce","type":"uint256"},{"internalType":"bytes","name":"initCode","type":"bytes"},{"internalType":"bytes","name":"callData","type":"bytes"},{"internalType":"bytes32","name":"accountGasLimits","type":"bytes32"},{"internalType":"uint256","name":"preVerificationGas","type":"uint256"},{"internalType":"bytes32","name":"gasFees","type":"bytes32"},{"internalType":"bytes","name":"paymasterAndData","type":"bytes"},{"internalType":"bytes","name":"signature","type":"bytes"}],"internalType":"struct PackedUserOperation[]","name":"ops","type":"tuple[]"},{"internalType":"address payable","name":"beneficiary","type":"address"}],"name":"handleOps","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"uint192","name":"key","type":"uint192"}],"name":"incrementNonce","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[],"name":"unlockStake","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address payable","name":"withdrawAddress","type":"address"}],"name":"withdrawStake","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address payable","name":"withdrawAddress","type":"address"},{"internalType":"uint256","name":"withdrawAmount","type":"uint256"}],"name":"withdrawTo","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// PackedUserOperation is the v0.7 UserOperation struct as encoded in EntryPoint calls.
+// accountGasLimits packs verificationGasLimit and callGasLimit, gasFees packs
+// maxPriorityFeePerGas and maxFeePerGas, each as two 16 byte values.
+type PackedUserOperation struct {
+	Sender             common.Address
+	Nonce              *big.Int
+	InitCode           []byte
+	CallData           []byte
+	AccountGasLimits   [32]byte
+	PreVerificationGas *big.Int
+	GasFees            [32]byte
+	PaymasterAndData   []byte
+	Signature          []byte
+}
+
+// EntryPointV07 is a binding for EntryPoint v0.7. The methods it exposes are
+// unchanged in v0.8, so it can also be bound to a v0.8 deployment.
+type EntryPointV07 struct {
+	entryPoint *EntryPoint
+}
+
+// NewEntryPointV07 creates a new EntryPointV07 instance
+func NewEntryPointV07(address common.Address, client *rpc.Client, chainId *big.Int) (*EntryPointV07, error) {
+	entryPoint, err := newEntryPoint(EntryPointV07Contract, address, client, chainId)
+	if err != nil {
+		return nil, err
+	}
+	return &EntryPointV07{entryPoint: entryPoint}, nil
+}
+
+// GetNonce returns the nonce of sender for the given nonce key
+func (e *EntryPointV07) GetNonce(ctx context.Context, sender common.Address, key *big.Int) (*big.Int, error) {
+	return e.entryPoint.GetNonce(ctx, sender, key)
+}
+
+// GetSenderAddress returns the counterfactual address of the account created by initCode
+func (e *EntryPointV07) GetSenderAddress(ctx context.Context, initCode []byte) (common.Address, error) {
+	return e.entryPoint.GetSenderAddress(ctx, initCode)
+}
+
+// GetUserOpHash returns the hash of op as computed by the EntryPoint
+func (e *EntryPointV07) GetUserOpHash(ctx context.Context, op PackedUserOperation) (common.Hash, error) {
+	output, err := e.entryPoint.call(ctx, "getUserOpHash", op)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	results, err := e.entryPoint.contractABI.Unpack("getUserOpHash", output)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return results[0].([32]byte), nil
+}
+
+// BalanceOf returns the deposit of account
+func (e *EntryPointV07) BalanceOf(ctx context.Context, account common.Address) (*big.Int, error) {
+	return e.entryPoint.BalanceOf(ctx, account)
+}
+
+// GetDepositInfo returns the deposit and stake information of account
+func (e *EntryPointV07) GetDepositInfo(ctx context.Context, account common.Address) (*DepositInfo, error) {
+	return e.entryPoint.GetDepositInfo(ctx, account)
+}
+
+// DepositTo adds opts.Value to the deposit of account
+func (e *EntryPointV07) DepositTo(opts *bind.TransactOpts, account common.Address) (*types.Transaction, error) {
+	return e.entryPoint.DepositTo(opts, account)
+}
+
+// WithdrawTo withdraws amount from the deposit of opts.From to withdrawAddress
+func (e *EntryPointV07) WithdrawTo(opts *bind.TransactOpts, withdrawAddress common.Address, amount *big.Int) (*types.Transaction, error) {
+	return e.entryPoint.WithdrawTo(opts, withdrawAddress, amount)
+}
+
+// AddStake adds opts.Value to the stake of opts.From with the given unstake delay
+func (e *EntryPointV07) AddStake(opts *bind.TransactOpts, unstakeDelaySec uint32) (*types.Transaction, error) {
+	return e.entryPoint.AddStake(opts, unstakeDelaySec)
+}
+
+// UnlockStake starts the unstake delay of opts.From
+func (e *EntryPointV07) UnlockStake(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return e.entryPoint.UnlockStake(opts)
+}
+
+// WithdrawStake withdraws the unlocked stake of opts.From to withdrawAddress
+func (e *EntryPointV07) WithdrawStake(opts *bind.TransactOpts, withdrawAddress common.Address) (*types.Transaction, error) {
+	return e.entryPoint.WithdrawStake(opts, withdrawAddress)
+}
@@ -0,0 +1,283 @@
+package typechain
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestEntryPoint binds an EntryPoint to a fake node. Each eth_call is
+// dispatched to calls by method name, which returns the packed outputs or
+// revert data.
+func newTestEntryPoint(t *testing.T, calls map[string]func(args []interface{}) (output []byte, revert []byte), sent *[]*types.Transaction) *EntryPoint {
+	t.Helper()
+	contractABI, err := abi.JSON(strings.NewReader(EntryPointContract))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_call":
+			var msg struct {
+				Input hexutil.Bytes `json:"input"`
+			}
+			if !assert.NoError(t, json.Unmarshal(req.Params[0], &msg)) {
+				return
+			}
+			method, err := contractABI.MethodById(msg.Input)
+			if !assert.NoError(t, err) {
+				return
+			}
+			args, err := method.Inputs.Unpack(msg.Input[4:])
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			output, revert := calls[method.Name](args)
+			if revert != nil {
+				resp["error"] = map[string]interface{}{"code": 3, "message": "execution reverted", "data": hexutil.Encode(revert)}
+			} else {
+				resp["result"] = hexutil.Encode(output)
+			}
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			if !assert.NoError(t, json.Unmarshal(req.Params[0], &raw)) {
+				return
+			}
+			tx := new(types.Transaction)
+			if !assert.NoError(t, tx.UnmarshalBinary(raw)) {
+				return
+			}
+			*sent = append(*sent, tx)
+			resp["result"] = tx.Hash()
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := rpc.Dial(server.URL)
+	require.NoError(t, err)
+	entryPoint, err := NewEntryPoint(common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"), client, big.NewInt(1))
+	require.NoError(t, err)
+	return entryPoint
+}
+
+func TestEntryPoint_Reads(t *testing.T) {
+	account := common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72")
+	userOpHash := common.HexToHash("0xdf9d96624c1b2cd0cb3b2ab2c2fa2a3ccbb0c63b0bcd1cd56ec8e1c5d67bce21")
+
+	var entryPoint *EntryPoint
+	entryPoint = newTestEntryPoint(t, map[string]func([]interface{}) ([]byte, []byte){
+		"getNonce": func(args []interface{}) ([]byte, []byte) {
+			assert.Equal(t, account, args[0])
+			assert.Equal(t, big.NewInt(2), args[1])
+			output, err := entryPoint.contractABI.Methods["getNonce"].Outputs.Pack(big.NewInt(7))
+			assert.NoError(t, err)
+			return output, nil
+		},
+		"balanceOf": func(args []interface{}) ([]byte, []byte) {
+			output, err := entryPoint.contractABI.Methods["balanceOf"].Outputs.Pack(big.NewInt(1e18))
+			assert.NoError(t, err)
+			return output, nil
+		},
+		"getDepositInfo": func(args []interface{}) ([]byte, []byte) {
+			output, err := entryPoint.contractABI.Methods["getDepositInfo"].Outputs.Pack(DepositInfo{
+				Deposit:         big.NewInt(1e18),
+				Staked:          true,
+				Stake:           big.NewInt(5e17),
+				UnstakeDelaySec: 86400,
+				WithdrawTime:    big.NewInt(0),
+			})
+			assert.NoError(t, err)
+			return output, nil
+		},
+		"getUserOpHash": func(args []interface{}) ([]byte, []byte) {
+			op := abi.ConvertType(args[0], new(UserOperation)).(*UserOperation)
+			assert.Equal(t, account, op.Sender)
+			assert.Equal(t, []byte{0x12, 0x34}, op.CallData)
+			return userOpHash.Bytes(), nil
+		},
+		"getSenderAddress": func(args []interface{}) ([]byte, []byte) {
+			senderAddressResult := entryPoint.contractABI.Errors["SenderAddressResult"]
+			revert, err := senderAddressResult.Inputs.Pack(account)
+			assert.NoError(t, err)
+			return nil, append(senderAddressResult.ID[:4], revert...)
+		},
+	}, nil)
+	ctx := context.Background()
+
+	nonce, err := entryPoint.GetNonce(ctx, account, big.NewInt(2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), nonce.Int64())
+
+	balance, err := entryPoint.BalanceOf(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1e18), balance)
+
+	info, err := entryPoint.GetDepositInfo(ctx, account)
+	require.NoError(t, err)
+	assert.True(t, info.Staked)
+	assert.Equal(t, big.NewInt(5e17), info.Stake)
+	assert.Equal(t, uint32(86400), info.UnstakeDelaySec)
+
+	hash, err := entryPoint.GetUserOpHash(ctx, UserOperation{
+		Sender:               account,
+		Nonce:                big.NewInt(0),
+		CallData:             []byte{0x12, 0x34},
+		CallGasLimit:         big.NewInt(0),
+		VerificationGasLimit: big.NewInt(0),
+		PreVerificationGas:   big.NewInt(0),
+		MaxFeePerGas:         big.NewInt(0),
+		MaxPriorityFeePerGas: big.NewInt(0),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, userOpHash, hash)
+
+	sender, err := entryPoint.GetSenderAddress(ctx, []byte{0x01})
+	require.NoError(t, err)
+	assert.Equal(t, account, sender)
+}
+
+func TestEntryPoint_DepositTo(t *testing.T) {
+	var sent []*types.Transaction
+	entryPoint := newTestEntryPoint(t, nil, &sent)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	opts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1))
+	require.NoError(t, err)
+	opts.Nonce = big.NewInt(3)
+	opts.GasPrice = big.NewInt(1e9)
+	opts.GasLimit = 100000
+	opts.Value = big.NewInt(1e16)
+
+	account := common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72")
+	tx, err := entryPoint.DepositTo(opts, account)
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, tx.Hash(), sent[0].Hash())
+	assert.Equal(t, entryPoint.contractAddress, *tx.To())
+	assert.Equal(t, big.NewInt(1e16), tx.Value())
+
+	args, err := entryPoint.contractABI.Methods["depositTo"].Inputs.Unpack(tx.Data()[4:])
+	require.NoError(t, err)
+	assert.Equal(t, account, args[0])
+}
+
+func TestEntryPoint_SimulateValidation(t *testing.T) {
+	returnInfo := ReturnInfo{
+		PreOpGas:         big.NewInt(60000),
+		Prefund:          big.NewInt(2e15),
+		ValidAfter:       big.NewInt(0),
+		ValidUntil:       big.NewInt(0),
+		PaymasterContext: []byte{},
+	}
+	stake := StakeInfo{Stake: big.NewInt(0), UnstakeDelaySec: big.NewInt(0)}
+	aggregator := AggregatorStakeInfo{
+		Aggregator: common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72"),
+		StakeInfo:  StakeInfo{Stake: big.NewInt(1e18), UnstakeDelaySec: big.NewInt(86400)},
+	}
+
+	var revert error
+	entryPoint := newTestEntryPoint(t, map[string]func([]interface{}) ([]byte, []byte){
+		"simulateValidation": func(args []interface{}) ([]byte, []byte) {
+			return nil, hexutil.MustDecode(revert.(*revertError).data)
+		},
+	}, nil)
+	op := UserOperation{
+		Nonce:                big.NewInt(0),
+		CallGasLimit:         big.NewInt(0),
+		VerificationGasLimit: big.NewInt(0),
+		PreVerificationGas:   big.NewInt(0),
+		MaxFeePerGas:         big.NewInt(0),
+		MaxPriorityFeePerGas: big.NewInt(0),
+	}
+
+	revert = packRevert(t, "ValidationResult", returnInfo, stake, stake, stake)
+	result, err := entryPoint.SimulateValidation(context.Background(), op)
+	require.NoError(t, err)
+	assert.Equal(t, int64(60000), result.ReturnInfo.PreOpGas.Int64())
+	assert.Equal(t, int64(2e15), result.ReturnInfo.Prefund.Int64())
+	assert.False(t, result.ReturnInfo.SigFailed)
+	assert.Nil(t, result.AggregatorInfo)
+
+	revert = packRevert(t, "ValidationResultWithAggregation", returnInfo, stake, stake, stake, aggregator)
+	result, err = entryPoint.SimulateValidation(context.Background(), op)
+	require.NoError(t, err)
+	require.NotNil(t, result.AggregatorInfo)
+	assert.Equal(t, aggregator.Aggregator, result.AggregatorInfo.Aggregator)
+	assert.Equal(t, int64(86400), result.AggregatorInfo.StakeInfo.UnstakeDelaySec.Int64())
+
+	revert = packRevert(t, "FailedOp", big.NewInt(0), "AA23 reverted (or OOG)")
+	_, err = entryPoint.SimulateValidation(context.Background(), op)
+	var failedOp *FailedOpError
+	require.ErrorAs(t, err, &failedOp)
+	assert.Equal(t, "AA23 reverted (or OOG)", failedOp.Reason)
+}
+
+func TestEntryPoint_SimulateHandleOp(t *testing.T) {
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	balanceABI, err := abi.JSON(strings.NewReader(`[{"inputs":[{"name":"account","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`))
+	require.NoError(t, err)
+	account := common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72")
+	balanceOf, err := balanceABI.Pack("balanceOf", account)
+	require.NoError(t, err)
+	balance, err := balanceABI.Methods["balanceOf"].Outputs.Pack(big.NewInt(500))
+	require.NoError(t, err)
+
+	entryPoint := newTestEntryPoint(t, map[string]func([]interface{}) ([]byte, []byte){
+		"simulateHandleOp": func(args []interface{}) ([]byte, []byte) {
+			assert.Equal(t, token, args[1])
+			assert.Equal(t, balanceOf, args[2])
+			revert := packRevert(t, "ExecutionResult", big.NewInt(70000), big.NewInt(3e15), big.NewInt(0), big.NewInt(0), true, balance)
+			return nil, hexutil.MustDecode(revert.(*revertError).data)
+		},
+	}, nil)
+
+	result, err := entryPoint.SimulateHandleOp(context.Background(), UserOperation{
+		Nonce:                big.NewInt(0),
+		CallGasLimit:         big.NewInt(0),
+		VerificationGasLimit: big.NewInt(0),
+		PreVerificationGas:   big.NewInt(0),
+		MaxFeePerGas:         big.NewInt(0),
+		MaxPriorityFeePerGas: big.NewInt(0),
+	}, token, balanceOf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(70000), result.PreOpGas.Int64())
+	assert.Equal(t, int64(3e15), result.Paid.Int64())
+	assert.True(t, result.TargetSuccess)
+
+	outputs, err := result.UnpackTargetResult(balanceABI, "balanceOf")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), outputs[0].(*big.Int).Int64())
+
+	result.TargetSuccess = false
+	_, err = result.UnpackTargetResult(balanceABI, "balanceOf")
+	assert.Error(t, err)
+}
@@ -65,11 +65,6 @@ func (m *Multisend) MultiSend(transactions []byte, privateKey string) (string, e
 	auth.GasPrice = gasPrice
 
 	// Call the multiSend function
-	tx, err := m.Abi.Pack("multiSend", transactions)
-	if err != nil {
-		return "", err
-	}
-
 	txObj, err := bind.NewBoundContract(m.Address, m.Abi, m.Client, m.Client, m.Client).Transact(auth, "multiSend", transactions)
 	if err != nil {
 		return "", err
@@ -0,0 +1,13 @@
+package typechain
+
+// SafeProxyFactoryContract is the ABI of the Safe 1.4.1 SafeProxyFactory
+const SafeProxyFactoryContract = `[{"inputs":[{"internalType":"address","name":"_singleton","type":"address"},{"internalType":"bytes","name":"initializer","type":"bytes"},{"internalType":"uint256","name":"saltNonce","type":"uint256"}],"name":"createProxyWithNonce","outputs":[{"internalType":"contract SafeProxy","name":"proxy","type":"address"}],"stateMutability":"nonpayable","type":"function"}]`
+
+// SafeContract is the ABI of the Safe 1.4.1 singleton setup function
+const SafeContract = `[{"inputs":[{"internalType":"address[]","name":"_owners","type":"address[]"},{"internalType":"uint256","name":"_threshold","type":"uint256"},{"internalType":"address","name":"to","type":"address"},{"internalType":"bytes","name":"data","type":"bytes"},{"internalType":"address","name":"fallbackHandler","type":"address"},{"internalType":"address","name":"paymentToken","type":"address"},{"internalType":"uint256","name":"payment","type":"uint256"},{"internalType":"address payable","name":"paymentReceiver","type":"address"}],"name":"setup","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// AddModulesLibContract is the ABI of the Safe 4337 AddModulesLib
+const AddModulesLibContract = `[{"inputs":[{"internalType":"address[]","name":"modules","type":"address[]"}],"name":"enableModules","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// Safe4337ModuleContract is the ABI of the Safe4337Module v0.2.0 execution functions
+const Safe4337ModuleContract = `[{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"bytes","name":"data","type":"bytes"},{"internalType":"uint8","name":"operation","type":"uint8"}],"name":"executeUserOp","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"bytes","name":"data","type":"bytes"},{"internalType":"uint8","name":"operation","type":"uint8"}],"name":"executeUserOpWithErrorString","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
@@ -1,11 +1,11 @@
 package userop
 
 import (
-	"encoding/json"
 	"math/big"
+	"net/http"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // IUserOperation represents an ERC-4337 User Operation.
@@ -21,6 +21,9 @@ type IUserOperation struct {
 	MaxPriorityFeePerGas *big.Int
 	PaymasterAndData     string
 	Signature            string
+	// Eip7702Auth is the EIP-7702 authorization sent with EntryPoint v0.8
+	// ops from delegated EOAs. It is nil for every other op.
+	Eip7702Auth *Eip7702Auth
 }
 
 // NewDefaultUserOperation creates a default IUserOperation.
@@ -57,6 +60,31 @@ func (op *IUserOperation) ToJSON() map[string]interface{} {
 	}
 }
 
+// Copy returns a deep copy of the IUserOperation.
+func (op *IUserOperation) Copy() *IUserOperation {
+	return &IUserOperation{
+		Sender:               op.Sender,
+		Nonce:                copyBigInt(op.Nonce),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         copyBigInt(op.CallGasLimit),
+		VerificationGasLimit: copyBigInt(op.VerificationGasLimit),
+		PreVerificationGas:   copyBigInt(op.PreVerificationGas),
+		MaxFeePerGas:         copyBigInt(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: copyBigInt(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+		Eip7702Auth:          op.Eip7702Auth.Copy(),
+	}
+}
+
+func copyBigInt(n *big.Int) *big.Int {
+	if n == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(n)
+}
+
 // IUserOperationBuilder provides a flexible way to construct an IUserOperation.
 type IUserOperationBuilder interface {
 	GetSender() common.Address
@@ -70,6 +98,8 @@ type IUserOperationBuilder interface {
 	GetMaxPriorityFeePerGas() *big.Int
 	GetPaymasterAndData() string
 	GetSignature() string
+	GetEip7702Auth() *Eip7702Auth
+	GetEntryPointVersion() EntryPointVersion
 	GetOp() *IUserOperation
 	SetSender(address common.Address) IUserOperationBuilder
 	SetNonce(nonce *big.Int) IUserOperationBuilder
@@ -82,11 +112,13 @@ type IUserOperationBuilder interface {
 	SetMaxPriorityFeePerGas(fee *big.Int) IUserOperationBuilder
 	SetPaymasterAndData(data string) IUserOperationBuilder
 	SetSignature(bytes string) IUserOperationBuilder
+	SetEip7702Auth(auth *Eip7702Auth) IUserOperationBuilder
 	SetPartial(partialOp map[string]interface{}) IUserOperationBuilder
 	UseDefaults(partialOp map[string]interface{}) IUserOperationBuilder
 	ResetDefaults() IUserOperationBuilder
 	UseMiddleware(fn UserOperationMiddlewareFn) IUserOperationBuilder
 	ResetMiddleware() IUserOperationBuilder
+	SetEntryPointVersion(version EntryPointVersion) IUserOperationBuilder
 	BuildOp(entryPoint common.Address, chainID *big.Int) (*IUserOperation, error)
 	ResetOp() IUserOperationBuilder
 }
@@ -96,23 +128,10 @@ type UserOperationMiddlewareFn func(ctx *IUserOperationMiddlewareCtx) error
 
 // IUserOperationMiddlewareCtx provides context for middleware functions.
 type IUserOperationMiddlewareCtx struct {
-	Op         *IUserOperation
-	EntryPoint common.Address
-	ChainID    *big.Int
-}
-
-// GetUserOpHash returns the hash of the user operation.
-func (ctx *IUserOperationMiddlewareCtx) GetUserOpHash() []byte {
-	// Implement hashing logic based on the user operation structure
-	// Example: Keccak256 of the serialized operation
-	hashInput := ctx.Op.ToJSON() // Serialize this correctly for hashing
-	hashInputBytes, err := json.Marshal(hashInput)
-	if err != nil {
-		// Handle error appropriately
-		return nil
-	}
-	hash := crypto.Keccak256Hash(hashInputBytes)
-	return hash.Bytes()
+	Op                *IUserOperation
+	EntryPoint        common.Address
+	ChainID           *big.Int
+	EntryPointVersion EntryPointVersion
 }
 
 // IClient represents an interface for the client class.
@@ -123,9 +142,40 @@ type IClient interface {
 
 // IClientOpts contains options for the client.
 type IClientOpts struct {
-	EntryPoint         common.Address
-	OverrideBundlerRpc string
-	SocketConnector    func() StreamChannel
+	EntryPoint              common.Address
+	EntryPointVersion       EntryPointVersion
+	OverrideBundlerRpc      string
+	SocketConnector         func() StreamChannel
+	RpcOpts                 *IEndpointOpts
+	BundlerRpcOpts          *IEndpointOpts
+	FallbackBundlerRpcs     []string
+	BroadcastUserOperations bool
+}
+
+// IProviderOpts contains the endpoints, routes and endpoint options of a BundlerJsonRpcProvider.
+type IProviderOpts struct {
+	Endpoints               map[string]string
+	Routes                  map[string]string
+	EndpointOpts            map[string]*IEndpointOpts
+	FailoverEndpoints       map[string][]string
+	BroadcastUserOperations bool
+}
+
+// IEndpointOpts contains connection options for an RPC endpoint; Timeout bounds the dial and each call.
+type IEndpointOpts struct {
+	Headers        map[string]string
+	HeaderProvider func(header http.Header) error
+	HTTPClient     *http.Client
+	Timeout        time.Duration
+}
+
+// IEndpointHealth reports the health of one URL of an endpoint. Failures
+// counts the consecutive calls the URL could not serve.
+type IEndpointHealth struct {
+	URL       string
+	Healthy   bool
+	Failures  int
+	LastError error
 }
 
 // ISendUserOperationOpts contains options for sending user operations.
@@ -135,9 +185,11 @@ type ISendUserOperationOpts struct {
 }
 
 // ISendUserOperationResponse represents the response for sendUserOperation.
+// BlockNumber is the latest block when the op was sent; Wait searches from it.
 type ISendUserOperationResponse struct {
-	UserOpHash string
-	Wait       func() (*FilterEvent, error)
+	UserOpHash  string
+	BlockNumber uint64
+	Wait        func() (*FilterEvent, error)
 }
 
 // IPresetBuilderOpts contains options for the preset builder.
@@ -148,6 +200,9 @@ type IPresetBuilderOpts struct {
 	PaymasterMiddleware UserOperationMiddlewareFn
 	NonceKey            *big.Int
 	OverrideBundlerRpc  string
+	RpcOpts             *IEndpointOpts
+	BundlerRpcOpts      *IEndpointOpts
+	FallbackBundlerRpcs []string
 }
 
 // Call represents a call operation.
@@ -164,7 +219,16 @@ type StreamChannel interface {
 	Receive() string
 }
 
-// FilterEvent represents the structure for filter events (define as needed)
+// FilterEvent is a decoded EntryPoint UserOperationEvent log.
 type FilterEvent struct {
-	// Add fields as necessary
+	UserOpHash      common.Hash
+	Sender          common.Address
+	Paymaster       common.Address
+	Nonce           *big.Int
+	Success         bool
+	ActualGasCost   *big.Int
+	ActualGasUsed   *big.Int
+	TransactionHash common.Hash
+	BlockHash       common.Hash
+	BlockNumber     uint64
 }
@@ -0,0 +1,213 @@
+package userop
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop/typechain"
+	"github.com/withsilasogar/userop/utils"
+)
+
+// paymasterDataOffset is the length of the paymaster address and the two
+// 16 byte gas limits that prefix paymasterAndData in EntryPoint v0.7.
+const paymasterDataOffset = common.AddressLength + 32
+
+// IUserOperationV07 is an EntryPoint v0.7 User Operation in the unpacked form
+// used by the bundler RPC. Factory and Paymaster are the zero address when
+// the op does not deploy an account or use a paymaster.
+type IUserOperationV07 struct {
+	Sender                        common.Address
+	Nonce                         *big.Int
+	Factory                       common.Address
+	FactoryData                   string
+	CallData                      string
+	CallGasLimit                  *big.Int
+	VerificationGasLimit          *big.Int
+	PreVerificationGas            *big.Int
+	MaxFeePerGas                  *big.Int
+	MaxPriorityFeePerGas          *big.Int
+	Paymaster                     common.Address
+	PaymasterVerificationGasLimit *big.Int
+	PaymasterPostOpGasLimit       *big.Int
+	PaymasterData                 string
+	Signature                     string
+	Eip7702Auth                   *Eip7702Auth
+}
+
+// ToV07 converts op into its v0.7 form. The initCode is split into factory
+// and factoryData, and paymasterAndData must use the v0.7 layout:
+// paymaster (20 bytes), paymasterVerificationGasLimit (16 bytes),
+// paymasterPostOpGasLimit (16 bytes), paymasterData.
+func (op *IUserOperation) ToV07() (*IUserOperationV07, error) {
+	initCode, err := hexutil.Decode(op.InitCode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid initCode: %w", err)
+	}
+	paymasterAndData, err := hexutil.Decode(op.PaymasterAndData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid paymasterAndData: %w", err)
+	}
+
+	v07 := &IUserOperationV07{
+		Sender:                        op.Sender,
+		Nonce:                         copyBigInt(op.Nonce),
+		FactoryData:                   "0x",
+		CallData:                      op.CallData,
+		CallGasLimit:                  copyBigInt(op.CallGasLimit),
+		VerificationGasLimit:          copyBigInt(op.VerificationGasLimit),
+		PreVerificationGas:            copyBigInt(op.PreVerificationGas),
+		MaxFeePerGas:                  copyBigInt(op.MaxFeePerGas),
+		MaxPriorityFeePerGas:          copyBigInt(op.MaxPriorityFeePerGas),
+		PaymasterVerificationGasLimit: big.NewInt(0),
+		PaymasterPostOpGasLimit:       big.NewInt(0),
+		PaymasterData:                 "0x",
+		Signature:                     op.Signature,
+		Eip7702Auth:                   op.Eip7702Auth.Copy(),
+	}
+	if isEip7702InitCode(initCode) && len(initCode) < common.AddressLength {
+		// A bare EIP-7702 marker is sent as the padded marker address.
+		initCode = common.RightPadBytes(initCode, common.AddressLength)
+	}
+	if len(initCode) > 0 {
+		if len(initCode) < common.AddressLength {
+			return nil, fmt.Errorf("initCode is shorter than a factory address")
+		}
+		v07.Factory = common.BytesToAddress(initCode[:common.AddressLength])
+		v07.FactoryData = hexutil.Encode(initCode[common.AddressLength:])
+	}
+	if len(paymasterAndData) > 0 {
+		if len(paymasterAndData) < paymasterDataOffset {
+			return nil, fmt.Errorf("paymasterAndData is shorter than %d bytes", paymasterDataOffset)
+		}
+		v07.Paymaster = common.BytesToAddress(paymasterAndData[:common.AddressLength])
+		v07.PaymasterVerificationGasLimit = new(big.Int).SetBytes(paymasterAndData[common.AddressLength : common.AddressLength+16])
+		v07.PaymasterPostOpGasLimit = new(big.Int).SetBytes(paymasterAndData[common.AddressLength+16 : paymasterDataOffset])
+		v07.PaymasterData = hexutil.Encode(paymasterAndData[paymasterDataOffset:])
+	}
+	return v07, nil
+}
+
+// ToJSON converts the IUserOperationV07 to the JSON-like map expected by
+// v0.7 and v0.8 bundlers. Factory, paymaster and eip7702Auth fields are left
+// out when unused.
+func (op *IUserOperationV07) ToJSON() map[string]interface{} {
+	json := map[string]interface{}{
+		"sender":               op.Sender.Hex(),
+		"nonce":                "0x" + op.Nonce.Text(16),
+		"callData":             op.CallData,
+		"callGasLimit":         "0x" + op.CallGasLimit.Text(16),
+		"verificationGasLimit": "0x" + op.VerificationGasLimit.Text(16),
+		"preVerificationGas":   "0x" + op.PreVerificationGas.Text(16),
+		"maxFeePerGas":         "0x" + op.MaxFeePerGas.Text(16),
+		"maxPriorityFeePerGas": "0x" + op.MaxPriorityFeePerGas.Text(16),
+		"signature":            op.Signature,
+	}
+	if op.Factory != (common.Address{}) {
+		json["factory"] = op.Factory.Hex()
+		json["factoryData"] = op.FactoryData
+	}
+	if op.Paymaster != (common.Address{}) {
+		json["paymaster"] = op.Paymaster.Hex()
+		json["paymasterVerificationGasLimit"] = "0x" + op.PaymasterVerificationGasLimit.Text(16)
+		json["paymasterPostOpGasLimit"] = "0x" + op.PaymasterPostOpGasLimit.Text(16)
+		json["paymasterData"] = op.PaymasterData
+	}
+	if op.Eip7702Auth != nil {
+		json["eip7702Auth"] = op.Eip7702Auth.ToJSON()
+	}
+	return json
+}
+
+// PackPaymasterAndData returns the v0.7 paymasterAndData for the split
+// paymaster fields returned by v0.7 paymasters.
+func PackPaymasterAndData(paymaster common.Address, verificationGasLimit, postOpGasLimit *big.Int, paymasterData string) (string, error) {
+	data, err := hexutil.Decode(paymasterData)
+	if err != nil {
+		return "", fmt.Errorf("invalid paymasterData: %w", err)
+	}
+	gasLimits, err := packUint128Pair(verificationGasLimit, postOpGasLimit)
+	if err != nil {
+		return "", err
+	}
+
+	packed := make([]byte, 0, paymasterDataOffset+len(data))
+	packed = append(packed, paymaster.Bytes()...)
+	packed = append(packed, gasLimits[:]...)
+	packed = append(packed, data...)
+	return hexutil.Encode(packed), nil
+}
+
+// ToPackedUserOperation converts op into the struct used by the EntryPoint v0.7 binding.
+func (op *IUserOperation) ToPackedUserOperation() (typechain.PackedUserOperation, error) {
+	unpacked, err := op.ToEntryPointUserOperation()
+	if err != nil {
+		return typechain.PackedUserOperation{}, err
+	}
+	accountGasLimits, err := packUint128Pair(op.VerificationGasLimit, op.CallGasLimit)
+	if err != nil {
+		return typechain.PackedUserOperation{}, fmt.Errorf("invalid gas limits: %w", err)
+	}
+	gasFees, err := packUint128Pair(op.MaxPriorityFeePerGas, op.MaxFeePerGas)
+	if err != nil {
+		return typechain.PackedUserOperation{}, fmt.Errorf("invalid gas fees: %w", err)
+	}
+
+	return typechain.PackedUserOperation{
+		Sender:             unpacked.Sender,
+		Nonce:              unpacked.Nonce,
+		InitCode:           unpacked.InitCode,
+		CallData:           unpacked.CallData,
+		AccountGasLimits:   accountGasLimits,
+		PreVerificationGas: unpacked.PreVerificationGas,
+		GasFees:            gasFees,
+		PaymasterAndData:   unpacked.PaymasterAndData,
+		Signature:          unpacked.Signature,
+	}, nil
+}
+
+// GetUserOpHashV07 computes the userOpHash exactly as EntryPoint v0.7
+// getUserOpHash does: keccak256(abi.encode(keccak256(pack(op)), entryPoint, chainId)).
+func GetUserOpHashV07(op *IUserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error) {
+	if chainID == nil {
+		return nil, fmt.Errorf("chain ID is required to compute the userOpHash")
+	}
+
+	packed, err := op.ToPackedUserOperation()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := utils.EncodeABI(
+		[]string{"address", "uint256", "bytes32", "bytes32", "bytes32", "uint256", "bytes32", "bytes32"},
+		[]interface{}{
+			packed.Sender,
+			packed.Nonce,
+			crypto.Keccak256Hash(packed.InitCode),
+			crypto.Keccak256Hash(packed.CallData),
+			packed.AccountGasLimits,
+			packed.PreVerificationGas,
+			packed.GasFees,
+			crypto.Keccak256Hash(packed.PaymasterAndData),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack user operation: %w", err)
+	}
+
+	return hashUserOp(encoded, entryPoint, chainID)
+}
+
+// packUint128Pair packs hi and lo into a bytes32 as two 16 byte values.
+func packUint128Pair(hi, lo *big.Int) ([32]byte, error) {
+	var packed [32]byte
+	for i, n := range []*big.Int{hi, lo} {
+		n = copyBigInt(n)
+		if n.Sign() < 0 || n.BitLen() > 128 {
+			return packed, fmt.Errorf("%s does not fit in uint128", n)
+		}
+		n.FillBytes(packed[i*16 : (i+1)*16])
+	}
+	return packed, nil
+}
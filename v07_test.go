@@ -0,0 +1,163 @@
+package userop
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop/constants"
+)
+
+// newTestOpV07 returns an op that deploys an account and uses a v0.7 paymaster.
+func newTestOpV07(t *testing.T) *IUserOperation {
+	t.Helper()
+	paymasterAndData, err := PackPaymasterAndData(common.HexToAddress("0xe93eca6595fe94091dc1af46aac2a8b5d7990770"), big.NewInt(60000), big.NewInt(10000), "0xabcd")
+	require.NoError(t, err)
+	return &IUserOperation{
+		Sender:               common.HexToAddress("0x8ba1f109551bD432803012645Ac136ddd64DBA72"),
+		Nonce:                big.NewInt(7),
+		InitCode:             "0x91e60e0613810449d098b0b5ec8b51a0fe8c89855fbfb9cf",
+		CallData:             "0x1234",
+		CallGasLimit:         big.NewInt(120000),
+		VerificationGasLimit: big.NewInt(450000),
+		PreVerificationGas:   big.NewInt(48000),
+		MaxFeePerGas:         big.NewInt(30000000000),
+		MaxPriorityFeePerGas: big.NewInt(1500000000),
+		PaymasterAndData:     paymasterAndData,
+		Signature:            "0x",
+	}
+}
+
+func TestToV07(t *testing.T) {
+	v07, err := newTestOpV07(t).ToV07()
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x91E60e0613810449d098b0b5Ec8b51A0FE8c8985"), v07.Factory)
+	assert.Equal(t, "0x5fbfb9cf", v07.FactoryData)
+	assert.Equal(t, common.HexToAddress("0xe93eca6595fe94091dc1af46aac2a8b5d7990770"), v07.Paymaster)
+	assert.Equal(t, int64(60000), v07.PaymasterVerificationGasLimit.Int64())
+	assert.Equal(t, int64(10000), v07.PaymasterPostOpGasLimit.Int64())
+	assert.Equal(t, "0xabcd", v07.PaymasterData)
+
+	opJSON := v07.ToJSON()
+	assert.Equal(t, v07.Factory.Hex(), opJSON["factory"])
+	assert.Equal(t, "0xea60", opJSON["paymasterVerificationGasLimit"])
+	assert.NotContains(t, opJSON, "initCode")
+	assert.NotContains(t, opJSON, "paymasterAndData")
+
+	// Ops without a factory or paymaster leave those fields out.
+	opJSON, err = OpToJSON(NewDefaultUserOperation(), EntryPointV07)
+	require.NoError(t, err)
+	assert.NotContains(t, opJSON, "factory")
+	assert.NotContains(t, opJSON, "paymaster")
+
+	op := NewDefaultUserOperation()
+	op.PaymasterAndData = "0xe93eca6595fe94091dc1af46aac2a8b5d7990770"
+	_, err = op.ToV07()
+	assert.Error(t, err, "a v0.6 paymasterAndData is too short for v0.7")
+}
+
+func TestGetUserOpHashV07(t *testing.T) {
+	op := newTestOpV07(t)
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT_V07)
+
+	// Encode the packed op independently from the PackedUserOperation layout.
+	bytes32, _ := abi.NewType("bytes32", "", nil)
+	uint256, _ := abi.NewType("uint256", "", nil)
+	address, _ := abi.NewType("address", "", nil)
+	args := abi.Arguments{{Type: address}, {Type: uint256}, {Type: bytes32}, {Type: bytes32}, {Type: bytes32}, {Type: uint256}, {Type: bytes32}, {Type: bytes32}}
+	var accountGasLimits, gasFees [32]byte
+	big.NewInt(450000).FillBytes(accountGasLimits[:16])
+	big.NewInt(120000).FillBytes(accountGasLimits[16:])
+	big.NewInt(1500000000).FillBytes(gasFees[:16])
+	big.NewInt(30000000000).FillBytes(gasFees[16:])
+	packed, err := args.Pack(
+		op.Sender,
+		op.Nonce,
+		crypto.Keccak256Hash(hexutil.MustDecode(op.InitCode)),
+		crypto.Keccak256Hash(hexutil.MustDecode(op.CallData)),
+		accountGasLimits,
+		op.PreVerificationGas,
+		gasFees,
+		crypto.Keccak256Hash(hexutil.MustDecode(op.PaymasterAndData)),
+	)
+	require.NoError(t, err)
+	outer, err := abi.Arguments{{Type: bytes32}, {Type: address}, {Type: uint256}}.Pack(crypto.Keccak256Hash(packed), entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+
+	hash, err := GetUserOpHashV07(op, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, crypto.Keccak256(outer), hash)
+	// Computed outside Go from UserOperationLib.encode and getUserOpHash of EntryPoint v0.7.
+	assert.Equal(t, "0xa284a5e0241f0955b3164edcc14ccbc6732da9b0bbbb2312bf3d797ad2ed7d2a", hexutil.Encode(hash))
+
+	ctx := &IUserOperationMiddlewareCtx{Op: op, EntryPoint: entryPoint, ChainID: big.NewInt(1)}
+	assert.Equal(t, EntryPointV07, ctx.Version())
+	ctxHash, err := ctx.GetUserOpHash()
+	require.NoError(t, err)
+	assert.Equal(t, hash, ctxHash)
+
+	v06Hash, err := GetUserOpHash(op, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, v06Hash)
+
+	op.CallGasLimit = new(big.Int).Lsh(big.NewInt(1), 128)
+	_, err = GetUserOpHashV07(op, entryPoint, big.NewInt(1))
+	assert.Error(t, err)
+}
+
+func TestBuildOp_EntryPointVersion(t *testing.T) {
+	var versions []EntryPointVersion
+	builder := NewUserOperationBuilder()
+	builder.UseMiddleware(func(ctx *IUserOperationMiddlewareCtx) error {
+		versions = append(versions, ctx.EntryPointVersion)
+		return nil
+	})
+
+	_, err := builder.BuildOp(common.HexToAddress(constants.ENTRY_POINT), big.NewInt(1))
+	require.NoError(t, err)
+	_, err = builder.BuildOp(common.HexToAddress(constants.ENTRY_POINT_V07), big.NewInt(1))
+	require.NoError(t, err)
+	builder.SetEntryPointVersion(EntryPointV07)
+	_, err = builder.BuildOp(common.HexToAddress("0x01"), big.NewInt(1))
+	require.NoError(t, err)
+
+	assert.Equal(t, []EntryPointVersion{EntryPointV06, EntryPointV07, EntryPointV07}, versions)
+}
+
+func TestClient_SendUserOperationV07(t *testing.T) {
+	server, _ := newTestRPCServer(t, map[string]rpcHandler{
+		"eth_chainId":     func([]json.RawMessage) (interface{}, error) { return "0x1", nil },
+		"eth_blockNumber": func([]json.RawMessage) (interface{}, error) { return "0x10", nil },
+		"eth_sendUserOperation": func(params []json.RawMessage) (interface{}, error) {
+			var op map[string]string
+			if err := decodeParams(params, &op, nil); err != nil {
+				return nil, err
+			}
+			assert.Equal(t, "0x91E60e0613810449d098b0b5Ec8b51A0FE8c8985", op["factory"])
+			assert.Equal(t, "0x5fbfb9cf", op["factoryData"])
+			assert.NotContains(t, op, "initCode")
+			return "0x01", nil
+		},
+	})
+	client, err := Init(server.URL, &IClientOpts{EntryPoint: common.HexToAddress(constants.ENTRY_POINT_V07)})
+	require.NoError(t, err)
+
+	builder := NewUserOperationBuilder()
+	builder.SetInitCode("0x91e60e0613810449d098b0b5ec8b51a0fe8c89855fbfb9cf")
+	_, err = client.SendUserOperation(builder, nil)
+	require.NoError(t, err)
+
+	builder.SetInitCode("0x91e60e0613810449d098b0b5ec8b51a0fe8c89855fbfb9cf")
+	var built *IUserOperation
+	res, err := client.SendUserOperation(builder, &ISendUserOperationOpts{DryRun: true, OnBuild: func(op *IUserOperation) { built = op }})
+	require.NoError(t, err)
+	expected, err := GetUserOpHashV07(built, common.HexToAddress(constants.ENTRY_POINT_V07), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, hexutil.Encode(expected), res.UserOpHash)
+}
@@ -0,0 +1,74 @@
+package userop
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/withsilasogar/userop/utils"
+)
+
+var (
+	eip712DomainTypeHash        = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	packedUserOperationTypeHash = crypto.Keccak256Hash([]byte("PackedUserOperation(address sender,uint256 nonce,bytes initCode,bytes callData,bytes32 accountGasLimits,uint256 preVerificationGas,bytes32 gasFees,bytes paymasterAndData)"))
+)
+
+// GetUserOpHashV08 computes the userOpHash exactly as EntryPoint v0.8
+// getUserOpHash does: the EIP-712 hash of the PackedUserOperation in the
+// "ERC4337" version "1" domain of entryPoint. For EIP-7702 ops the marker in
+// the initCode is replaced with the delegate from op.Eip7702Auth.
+func GetUserOpHashV08(op *IUserOperation, entryPoint common.Address, chainID *big.Int) ([]byte, error) {
+	if chainID == nil {
+		return nil, fmt.Errorf("chain ID is required to compute the userOpHash")
+	}
+
+	packed, err := op.ToPackedUserOperation()
+	if err != nil {
+		return nil, err
+	}
+	initCodeHash := crypto.Keccak256Hash(packed.InitCode)
+	if isEip7702InitCode(packed.InitCode) {
+		if op.Eip7702Auth == nil {
+			return nil, fmt.Errorf("eip7702Auth is required to hash an EIP-7702 user operation")
+		}
+		initCode := op.Eip7702Auth.Address.Bytes()
+		if len(packed.InitCode) > common.AddressLength {
+			initCode = append(initCode, packed.InitCode[common.AddressLength:]...)
+		}
+		initCodeHash = crypto.Keccak256Hash(initCode)
+	}
+
+	domain, err := utils.EncodeABI(
+		[]string{"bytes32", "bytes32", "bytes32", "uint256", "address"},
+		[]interface{}{
+			eip712DomainTypeHash,
+			crypto.Keccak256Hash([]byte("ERC4337")),
+			crypto.Keccak256Hash([]byte("1")),
+			chainID,
+			entryPoint,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode domain: %w", err)
+	}
+	structHash, err := utils.EncodeABI(
+		[]string{"bytes32", "address", "uint256", "bytes32", "bytes32", "bytes32", "uint256", "bytes32", "bytes32"},
+		[]interface{}{
+			packedUserOperationTypeHash,
+			packed.Sender,
+			packed.Nonce,
+			initCodeHash,
+			crypto.Keccak256Hash(packed.CallData),
+			packed.AccountGasLimits,
+			packed.PreVerificationGas,
+			packed.GasFees,
+			crypto.Keccak256Hash(packed.PaymasterAndData),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack user operation: %w", err)
+	}
+
+	return crypto.Keccak256([]byte{0x19, 0x01}, crypto.Keccak256(domain), crypto.Keccak256(structHash)), nil
+}
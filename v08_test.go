@@ -0,0 +1,139 @@
+package userop
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/withsilasogar/userop/constants"
+)
+
+func TestGetUserOpHashV08(t *testing.T) {
+	op := newTestOpV07(t)
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT_V08)
+	packed, err := op.ToPackedUserOperation()
+	require.NoError(t, err)
+
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PackedUserOperation": {
+				{Name: "sender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "initCode", Type: "bytes"},
+				{Name: "callData", Type: "bytes"},
+				{Name: "accountGasLimits", Type: "bytes32"},
+				{Name: "preVerificationGas", Type: "uint256"},
+				{Name: "gasFees", Type: "bytes32"},
+				{Name: "paymasterAndData", Type: "bytes"},
+			},
+		},
+		PrimaryType: "PackedUserOperation",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "ERC4337",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(1),
+			VerifyingContract: entryPoint.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"sender":             op.Sender.Hex(),
+			"nonce":              op.Nonce.String(),
+			"initCode":           op.InitCode,
+			"callData":           op.CallData,
+			"accountGasLimits":   hexutil.Encode(packed.AccountGasLimits[:]),
+			"preVerificationGas": op.PreVerificationGas.String(),
+			"gasFees":            hexutil.Encode(packed.GasFees[:]),
+			"paymasterAndData":   op.PaymasterAndData,
+		},
+	}
+	expected, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+
+	hash, err := GetUserOpHashV08(op, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, expected, hash)
+
+	ctx := &IUserOperationMiddlewareCtx{Op: op, EntryPoint: entryPoint, ChainID: big.NewInt(1)}
+	assert.Equal(t, EntryPointV08, ctx.Version())
+	ctxHash, err := ctx.GetUserOpHash()
+	require.NoError(t, err)
+	assert.Equal(t, hash, ctxHash)
+}
+
+func TestGetUserOpHashV08_Eip7702(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	delegate := common.HexToAddress("0xe6Cae83BdE06E4c305530e199D7217f42808555B")
+	auth, err := SignEip7702Auth(signer, big.NewInt(1), delegate, 4)
+	require.NoError(t, err)
+
+	authority, err := auth.Authority()
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(signer.PublicKey), authority)
+
+	entryPoint := common.HexToAddress(constants.ENTRY_POINT_V08)
+	op := NewDefaultUserOperation()
+	op.Sender = authority
+	op.InitCode = "0x7702"
+
+	_, err = GetUserOpHashV08(op, entryPoint, big.NewInt(1))
+	assert.Error(t, err, "the delegate is needed to hash an EIP-7702 op")
+
+	// The EntryPoint hashes the delegate in place of the marker.
+	op.Eip7702Auth = auth
+	hash, err := GetUserOpHashV08(op, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	delegated := op.Copy()
+	delegated.InitCode = hexutil.Encode(delegate.Bytes())
+	delegated.Eip7702Auth = nil
+	expected, err := GetUserOpHashV08(delegated, entryPoint, big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, expected, hash)
+
+	opJSON, err := OpToJSON(op, EntryPointV08)
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress(constants.EIP7702_INITCODE_MARKER).Hex(), opJSON["factory"])
+	assert.Equal(t, "0x", opJSON["factoryData"])
+	raw, err := json.Marshal(opJSON["eip7702Auth"])
+	require.NoError(t, err)
+	var decoded struct {
+		ChainID hexutil.Big    `json:"chainId"`
+		Address common.Address `json:"address"`
+		Nonce   hexutil.Uint64 `json:"nonce"`
+		YParity hexutil.Uint64 `json:"yParity"`
+		R       hexutil.Big    `json:"r"`
+		S       hexutil.Big    `json:"s"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, delegate, decoded.Address)
+	assert.Equal(t, uint64(4), uint64(decoded.Nonce))
+	assert.Equal(t, auth.R, decoded.R.ToInt())
+}
+
+func TestEip7702Auth_Copy(t *testing.T) {
+	signer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	auth, err := SignEip7702Auth(signer, big.NewInt(1), common.HexToAddress("0x01"), 0)
+	require.NoError(t, err)
+
+	builder := NewUserOperationBuilder()
+	builder.SetEip7702Auth(auth)
+	auth.Nonce = 9
+	assert.Equal(t, uint64(0), builder.GetEip7702Auth().Nonce)
+
+	op, err := builder.BuildOp(common.HexToAddress(constants.ENTRY_POINT_V08), big.NewInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x01"), op.Eip7702Auth.Address)
+}
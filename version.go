@@ -0,0 +1,37 @@
+package userop
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/withsilasogar/userop/constants"
+)
+
+// EntryPointVersion identifies the EntryPoint release an op is built for.
+// It decides the hash algorithm and the JSON shape sent to the bundler.
+type EntryPointVersion string
+
+const (
+	EntryPointV06 EntryPointVersion = "v0.6"
+	EntryPointV07 EntryPointVersion = "v0.7"
+	EntryPointV08 EntryPointVersion = "v0.8"
+)
+
+// GetEntryPointVersion returns the version of a known EntryPoint deployment.
+// Unknown addresses are assumed to be v0.6.
+func GetEntryPointVersion(entryPoint common.Address) EntryPointVersion {
+	switch entryPoint {
+	case common.HexToAddress(constants.ENTRY_POINT_V07):
+		return EntryPointV07
+	case common.HexToAddress(constants.ENTRY_POINT_V08):
+		return EntryPointV08
+	default:
+		return EntryPointV06
+	}
+}
+
+// resolveEntryPointVersion returns version if set, otherwise the version of entryPoint.
+func resolveEntryPointVersion(version EntryPointVersion, entryPoint common.Address) EntryPointVersion {
+	if version != "" {
+		return version
+	}
+	return GetEntryPointVersion(entryPoint)
+}